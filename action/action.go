@@ -3,14 +3,20 @@ package action
 import (
 	"bytes"
 	"encoding/json"
-	"errors"
 	"io/ioutil"
 	"net/http"
+	"time"
 
 	"github.com/botopolis/bot"
+	adapter "github.com/botopolis/slack"
+	"github.com/botopolis/slack/internal/signing"
 	"github.com/nlopes/slack"
 )
 
+// defaultDeadline leaves some room before Slack gives up on a webhook
+// after 3 seconds
+const defaultDeadline = 2500 * time.Millisecond
+
 // Plugin conforms to the botopolis/bot.Plugin interface
 type Plugin struct {
 	*registry
@@ -18,6 +24,12 @@ type Plugin struct {
 	Path string
 	// Signing secret to verify message comes from slack.
 	SigningSecret string
+	adapter.Verification
+	// Token is the bot token used to open and update views.
+	Token string
+	// Deadline is how long we wait for a handler's response before
+	// answering Slack without it. Defaults to 2.5 seconds.
+	Deadline time.Duration
 
 	logger bot.Logger
 }
@@ -47,36 +59,147 @@ func (p Plugin) webhook(w http.ResponseWriter, r *http.Request) {
 	r.Body = ioutil.NopCloser(bytes.NewBuffer(b))
 	p.logger.Debugf("slack/action: Received webhook to %s\n", p.Path)
 
-	if err := p.verify(r.Header, b); err != nil {
+	secrets, err := signing.Secrets(p.SigningSecret, p.SigningSecrets, p.SecretsProvider)
+	if err != nil {
+		p.logger.Errorf("slack/action: Unable to get signing secrets: %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	matched, err := signing.Verify(r.Header, b, secrets, p.Tolerance)
+	if err != nil {
 		p.logger.Errorf("slack/action: Invalid webhook: %v\n", err)
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
+	p.logger.Debugf("slack/action: Webhook signed with secret %d of %d\n", matched+1, len(secrets))
 
 	jsonBody := []byte(r.FormValue("payload"))
-	var cb slack.AttachmentActionCallback
-	if err := json.Unmarshal(jsonBody, &cb); err != nil {
-		p.logger.Errorf("slack/action: Invalid webhook: %v\n", err)
-		w.WriteHeader(http.StatusBadRequest)
+	decode := func(v interface{}) bool {
+		if err := json.Unmarshal(jsonBody, v); err != nil {
+			p.logger.Errorf("slack/action: Invalid webhook: %v\n", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return false
+		}
+		return true
+	}
+
+	var head struct {
+		Type string `json:"type"`
+	}
+	if !decode(&head) {
 		return
 	}
 
-	go p.Run(cb)
+	switch head.Type {
+	case "block_actions":
+		var cb BlockActionCallback
+		if decode(&cb) {
+			p.Responder(cb.ResponseURL)
+			go p.RunBlockActions(cb)
+		}
+	case "view_submission":
+		var cb ViewSubmissionCallback
+		if decode(&cb) {
+			p.respondView(w, cb)
+		}
+	case "block_suggestion", "dialog_suggestion":
+		var req OptionsRequest
+		if decode(&req) {
+			p.respondOptions(w, req)
+		}
+	case "shortcut":
+		var cb ShortcutCallback
+		if decode(&cb) {
+			go p.RunShortcut(cb)
+		}
+	case "message_action":
+		var cb ShortcutCallback
+		if !decode(&cb) {
+			return
+		}
+		p.Responder(cb.ResponseURL)
+		if p.hasShortcut(cb.CallbackID) {
+			go p.RunShortcut(cb)
+			return
+		}
+		// Message actions used to be handled like attachment actions
+		var legacy slack.AttachmentActionCallback
+		if decode(&legacy) {
+			p.respond(w, legacy)
+		}
+	case "view_closed":
+		var cb ViewClosedCallback
+		if decode(&cb) {
+			go p.RunViewClosed(cb)
+		}
+	default:
+		var cb slack.AttachmentActionCallback
+		if decode(&cb) {
+			p.Responder(cb.ResponseURL)
+			p.respond(w, cb)
+		}
+	}
 }
 
-func (p Plugin) verify(h http.Header, body []byte) error {
-	if h["X-Slack-Signature"] == nil || h["X-Slack-Request-Timestamp"] == nil {
-		return errors.New("Missing signing headers")
+func (p Plugin) deadline() time.Duration {
+	if p.Deadline > 0 {
+		return p.Deadline
 	}
+	return defaultDeadline
+}
 
-	verifier, err := slack.NewSecretsVerifier(h, p.SigningSecret)
-	if err != nil {
-		return err
+// respond runs the callback and sends back its response. When the
+// callback takes longer than the deadline, a replacement message is
+// posted to the response_url once it's done instead.
+func (p Plugin) respond(w http.ResponseWriter, cb slack.AttachmentActionCallback) {
+	done := make(chan *Response, 1)
+	go func() { done <- p.Run(cb) }()
+
+	select {
+	case resp := <-done:
+		if resp != nil {
+			p.write(w, cb.CallbackID, resp)
+		}
+	case <-time.After(p.deadline()):
+		go p.respondLater(cb, done)
 	}
+}
 
-	if _, err := verifier.Write(body); err != nil {
-		return err
+func (p Plugin) respondLater(cb slack.AttachmentActionCallback, done <-chan *Response) {
+	resp := <-done
+	switch {
+	case resp == nil:
+	case resp.Message != nil:
+		if err := p.Responder(cb.ResponseURL).Replace(*resp.Message); err != nil {
+			p.logger.Errorf("slack/action: Unable to respond to %s: %v\n", cb.CallbackID, err)
+		}
+	default:
+		p.logger.Errorf("slack/action: Callback %s took longer than %s to respond\n", cb.CallbackID, p.deadline())
 	}
+}
 
-	return verifier.Ensure()
+// respondView runs the view_submission handler and sends back its
+// response. Handlers which take too long are left to finish on their
+// own, and the modal is closed.
+func (p Plugin) respondView(w http.ResponseWriter, cb ViewSubmissionCallback) {
+	done := make(chan *ViewResponse, 1)
+	go func() { done <- p.RunView(cb) }()
+
+	select {
+	case resp := <-done:
+		if resp != nil {
+			p.write(w, cb.View.CallbackID, resp)
+		}
+	case <-time.After(p.deadline()):
+		p.logger.Errorf("slack/action: View %s took longer than %s to respond\n", cb.View.CallbackID, p.deadline())
+	}
+}
+
+// write sends v as the body of our answer to Slack
+func (p Plugin) write(w http.ResponseWriter, callbackID string, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		p.logger.Errorf("slack/action: Unable to respond to %s: %v\n", callbackID, err)
+	}
 }
@@ -1,43 +1,34 @@
 package action
 
 import (
-	"bytes"
 	"fmt"
-	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/botopolis/bot/mock"
+	"github.com/botopolis/slack/internal/signing"
+	"github.com/botopolis/slack/internal/signing/signingtest"
 	"github.com/nlopes/slack"
 	"github.com/stretchr/testify/assert"
 )
 
-const (
-	signingSecret = "e6b19c573432dcc6b075501d51b51bb8"
+const signingSecret = signingtest.Secret
 
-	fooBody      = `payload=%7B%22callback_id%22%3A%22foo%22%7D`
-	fooSignature = "v0=d27668944a2857e8495256fc93c7aed9f1119617ec08902b56edf69862b16855"
-	barBody      = `payload=%7B%22callback_id%22%3A%22bar%22%7D`
-	barSignature = "v0=50179568ccb23da3e0dd88c0ac6da9e336ae9ed2895008d7d736807978e4e8bf"
-)
-
-func newHeader(signature string) http.Header {
-	h := http.Header{}
-	h.Set("Content-Type", "application/x-www-form-urlencoded")
-	h.Set("X-Slack-Signature", signature)
-	h.Set("X-Slack-Request-Timestamp", "1531431954")
-	return h
-}
-
-func readCloser(b []byte) io.ReadCloser {
-	return ioutil.NopCloser(bytes.NewReader(b))
+// withSignature is a signed request whose signature was swapped for
+// signature
+func withSignature(r *http.Request, signature string) *http.Request {
+	r.Header.Set("X-Slack-Signature", signature)
+	return r
 }
 
 var logger = mock.NewLogger()
 
 func init() {
+	signing.Default.Now = signingtest.Now
+
 	logger.WriteFunc = func(l mock.Level, v ...interface{}) {
 		fmt.Println(v...)
 	}
@@ -48,34 +39,29 @@ func init() {
 
 func TestWebhook_response(t *testing.T) {
 	cases := []struct {
-		Name   string
-		Body   []byte
-		Header http.Header
-		Out    int
+		Name string
+		Req  *http.Request
+		Out  int
 	}{
 		{
-			Name:   "With an empty header",
-			Body:   []byte(`payload=%7B%22token%22%3A%22foo%22%7D`),
-			Header: newHeader(""),
-			Out:    http.StatusBadRequest,
+			Name: "With an empty header",
+			Req:  withSignature(signedRequest(`{"token":"foo"}`), ""),
+			Out:  http.StatusBadRequest,
 		},
 		{
-			Name:   "With a non-JSON body",
-			Body:   []byte(`<xml></xml>`),
-			Header: newHeader("v0=242c2f40d58a5dbe4ae2d73ff61e07cf632a1d43a8e52a714e04e3fc4889cb7f"),
-			Out:    http.StatusBadRequest,
+			Name: "With a non-JSON body",
+			Req:  signingtest.Request(signingSecret, `<xml></xml>`),
+			Out:  http.StatusBadRequest,
 		},
 		{
-			Name:   "With a bad header",
-			Body:   []byte(`payload=%7B%22token%22%3A%22foo%22%7D`),
-			Header: newHeader("bad header"),
-			Out:    http.StatusBadRequest,
+			Name: "With a bad header",
+			Req:  withSignature(signedRequest(`{"token":"foo"}`), "bad header"),
+			Out:  http.StatusBadRequest,
 		},
 		{
-			Name:   "When everything's right",
-			Body:   []byte(`payload=%7B%22token%22%3A%22foo%22%7D`),
-			Header: newHeader("v0=9bff0c9ad4804e518f3bc03b7a8b3d3e360b78b368e601294574cc10878e7c13"),
-			Out:    http.StatusOK,
+			Name: "When everything's right",
+			Req:  signedRequest(`{"token":"foo"}`),
+			Out:  http.StatusOK,
 		},
 	}
 
@@ -83,11 +69,7 @@ func TestWebhook_response(t *testing.T) {
 	for _, c := range cases {
 		t.Run(c.Name, func(t *testing.T) {
 			recorder := httptest.NewRecorder()
-			p.webhook(recorder, &http.Request{
-				Body:   readCloser(c.Body),
-				Header: c.Header,
-				Method: "POST",
-			})
+			p.webhook(recorder, c.Req)
 			assert.Equal(t, c.Out, recorder.Code)
 		})
 	}
@@ -95,24 +77,73 @@ func TestWebhook_response(t *testing.T) {
 
 func TestWebhook_callback(t *testing.T) {
 	done := make(chan string, 3)
-	fooReq := http.Request{
-		Header: newHeader(fooSignature),
-		Body:   readCloser([]byte(fooBody)),
-		Method: "POST",
-	}
-	barReq := http.Request{
-		Header: newHeader(barSignature),
-		Body:   readCloser([]byte(barBody)),
-		Method: "POST",
-	}
-
 	p := Plugin{SigningSecret: signingSecret, registry: &registry{}, logger: logger}
 	p.Add("bar", func(slack.AttachmentActionCallback) { done <- "bar" })
 	p.Add("foo", func(slack.AttachmentActionCallback) { done <- "foo" })
 
-	p.webhook(httptest.NewRecorder(), &fooReq)
+	p.webhook(httptest.NewRecorder(), signedRequest(`{"callback_id":"foo"}`))
 	assert.Equal(t, "foo", <-done)
 
-	p.webhook(httptest.NewRecorder(), &barReq)
+	p.webhook(httptest.NewRecorder(), signedRequest(`{"callback_id":"bar"}`))
 	assert.Equal(t, "bar", <-done)
 }
+
+func TestWebhook_blockActions(t *testing.T) {
+	done := make(chan BlockAction, 1)
+	p := Plugin{SigningSecret: signingSecret, registry: &registry{}, logger: logger}
+	p.Add("approve", func(slack.AttachmentActionCallback) { t.Error("legacy callback run") })
+	p.AddBlockAction("approve", func(cb BlockActionCallback, a BlockAction) {
+		assert.Equal(t, "block_actions", cb.Type)
+		done <- a
+	})
+
+	recorder := httptest.NewRecorder()
+	p.webhook(recorder, signedRequest(`{"type":"block_actions","actions":[{"action_id":"approve","block_id":"b1","value":"yes"}]}`))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	a := <-done
+	assert.Equal(t, "b1", a.BlockID)
+	assert.Equal(t, "yes", a.Value)
+}
+
+func TestWebhook_callbackResponse(t *testing.T) {
+	p := Plugin{SigningSecret: signingSecret, registry: &registry{}, logger: logger}
+	p.AddWithResponse("approve", func(slack.AttachmentActionCallback) *Response {
+		return &Response{Message: &Message{Text: "Approved"}}
+	})
+	p.AddWithResponse("dialog", func(slack.AttachmentActionCallback) *Response {
+		return &Response{Errors: map[string]string{"name": "Required", "email": "Invalid"}}
+	})
+
+	recorder := httptest.NewRecorder()
+	p.webhook(recorder, signedRequest(`{"callback_id":"approve"}`))
+	assert.JSONEq(t, `{"text":"Approved","replace_original":true}`, recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	p.webhook(recorder, signedRequest(`{"type":"dialog_submission","callback_id":"dialog"}`))
+	assert.JSONEq(t, `{"errors":[{"name":"email","error":"Invalid"},{"name":"name","error":"Required"}]}`, recorder.Body.String())
+}
+
+func TestWebhook_callbackResponseLate(t *testing.T) {
+	posted := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		posted <- string(b)
+	}))
+	defer server.Close()
+
+	release := make(chan bool)
+	p := Plugin{SigningSecret: signingSecret, registry: &registry{}, logger: logger, Deadline: time.Millisecond}
+	p.AddWithResponse("approve", func(slack.AttachmentActionCallback) *Response {
+		<-release
+		return &Response{Message: &Message{Text: "Approved"}}
+	})
+
+	recorder := httptest.NewRecorder()
+	p.webhook(recorder, signedRequest(`{"callback_id":"approve","response_url":"`+server.URL+`"}`))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Empty(t, recorder.Body.String())
+
+	close(release)
+	assert.JSONEq(t, `{"text":"Approved","replace_original":true}`, <-posted)
+}
@@ -0,0 +1,48 @@
+package action
+
+import (
+	adapter "github.com/botopolis/slack"
+	"github.com/nlopes/slack"
+)
+
+// BlockActionCallback is the payload Slack sends when someone interacts
+// with a Block Kit element, such as clicking a button.
+type BlockActionCallback struct {
+	Type        string        `json:"type"`
+	Team        slack.Team    `json:"team"`
+	User        slack.User    `json:"user"`
+	Channel     slack.Channel `json:"channel"`
+	Message     slack.Message `json:"message"`
+	Container   Container     `json:"container"`
+	APIAppID    string        `json:"api_app_id"`
+	TriggerID   string        `json:"trigger_id"`
+	ResponseURL string        `json:"response_url"`
+	Actions     []BlockAction `json:"actions"`
+}
+
+// Container is where the interaction happened: a message or a view
+type Container struct {
+	Type        string `json:"type"`
+	MessageTS   string `json:"message_ts"`
+	ChannelID   string `json:"channel_id"`
+	IsEphemeral bool   `json:"is_ephemeral"`
+	ViewID      string `json:"view_id"`
+}
+
+// BlockAction is a single interaction within a BlockActionCallback
+type BlockAction struct {
+	ActionID string       `json:"action_id"`
+	BlockID  string       `json:"block_id"`
+	Type     string       `json:"type"`
+	ActionTS string       `json:"action_ts"`
+	Text     adapter.Text `json:"text"`
+	// Value of a button
+	Value string `json:"value"`
+
+	SelectedOption       adapter.Option   `json:"selected_option"`
+	SelectedOptions      []adapter.Option `json:"selected_options"`
+	SelectedUser         string           `json:"selected_user"`
+	SelectedChannel      string           `json:"selected_channel"`
+	SelectedConversation string           `json:"selected_conversation"`
+	SelectedDate         string           `json:"selected_date"`
+}
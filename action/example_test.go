@@ -2,8 +2,10 @@ package action_test
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/botopolis/bot"
+	"github.com/botopolis/slack"
 	"github.com/botopolis/slack/action"
 	oslack "github.com/nlopes/slack"
 )
@@ -19,6 +21,9 @@ func (p ExamplePlugin) Load(r *bot.Robot) {
 		return
 	}
 
+	// recover from panics in, and time, every callback
+	actions.Use(action.Recover(r.Logger), action.Timing(r.Logger))
+
 	r.Hear(bot.Regexp("trigger"), func(r bot.Responder) error {
 		return r.Send(bot.Message{
 			Params: oslack.PostMessageParameters{
@@ -48,8 +53,76 @@ func (p ExamplePlugin) Load(r *bot.Robot) {
 			return
 		}
 		if a.Actions[0].Value == "true" {
-			// do the thing
+			// do the thing, then let everyone know
+			actions.Responder(a.ResponseURL).Replace(action.Message{Text: "Done!"})
+		}
+	})
+
+	r.Hear(bot.Regexp("deploy"), func(r bot.Responder) error {
+		return r.Send(bot.Message{
+			Params: slack.Blocks{Blocks: []slack.Block{
+				slack.Section{Text: slack.Markdown("Deploy to production?")},
+				slack.Actions{BlockID: "deploy", Elements: []slack.Element{
+					slack.Button{ActionID: "approve", Text: "Deploy", Style: "primary", Value: "production"},
+				}},
+			}},
+		})
+	})
+
+	// answer straight away with a replacement message
+	actions.AddWithResponse("vote", func(a oslack.AttachmentActionCallback) *action.Response {
+		return &action.Response{Message: &action.Message{Text: a.User.Name + " voted " + a.Actions[0].Value}}
+	})
+
+	// handle Block Kit buttons by action ID, optionally within a block
+	actions.AddBlock("deploy", "approve", func(cb action.BlockActionCallback, a action.BlockAction) {
+		fmt.Println(cb.User.Name, "deployed to", a.Value)
+	}, action.AllowUsers("U024BE7LH", "U0G9QF9C6"))
+
+	// open a modal when a button is clicked, and validate what is submitted
+	actions.AddBlockAction("incident", func(cb action.BlockActionCallback, a action.BlockAction) {
+		actions.OpenView(cb.TriggerID, action.View{
+			Title:      "Report an incident",
+			Submit:     "Report",
+			CallbackID: "incident",
+			Blocks: []slack.Block{slack.Input{
+				BlockID: "summary",
+				Label:   "What's broken?",
+				Element: slack.PlainTextInput{ActionID: "text"},
+			}},
+		})
+	})
+	// file a message as a bug from its "…" menu
+	actions.AddShortcut("file_bug", func(cb action.ShortcutCallback) {
+		actions.OpenView(cb.TriggerID, action.View{
+			Title:           "File a bug",
+			Submit:          "File",
+			CallbackID:      "bug",
+			PrivateMetadata: cb.Channel.ID + "/" + cb.Message.Timestamp,
+			Blocks: []slack.Block{slack.Input{
+				BlockID: "title",
+				Label:   "Title",
+				Element: slack.PlainTextInput{ActionID: "text", InitialValue: cb.Message.Text},
+			}},
+		})
+	})
+
+	// load the options of an external select menu as the user types
+	actions.AddOptions("service", func(req action.OptionsRequest) action.Options {
+		var opts action.Options
+		for _, name := range []string{"api", "payments", "search"} {
+			if strings.HasPrefix(name, req.Value) {
+				opts.Options = append(opts.Options, slack.Option{Text: name, Value: name})
+			}
+		}
+		return opts
+	})
+	actions.AddView("incident", func(cb action.ViewSubmissionCallback) *action.ViewResponse {
+		summary, _ := cb.View.Value("summary", "text")
+		if len(summary.Value) < 10 {
+			return action.ViewErrors(map[string]string{"summary": "Tell us a bit more"})
 		}
+		return nil
 	})
 }
 
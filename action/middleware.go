@@ -0,0 +1,97 @@
+package action
+
+import (
+	"runtime/debug"
+	"time"
+
+	"github.com/botopolis/bot"
+)
+
+// Interaction is what middleware sees of an interaction as it's
+// dispatched to its handler
+type Interaction struct {
+	// Type is the payload's type, such as block_actions or view_submission.
+	// It's interactive_message for callbacks registered with Add.
+	Type string
+	// ID is what the handler was registered with: a callback ID, or the
+	// action ID for block actions and options
+	ID        string
+	UserID    string
+	ChannelID string
+	// Payload is the callback being handled, such as a BlockActionCallback
+	Payload interface{}
+	// Action is the element interacted with, for block actions
+	Action BlockAction
+}
+
+// Handler handles an interaction, returning what the registered callback
+// answered with (a *Response, *ViewResponse or Options), if anything
+type Handler func(Interaction) interface{}
+
+// Middleware wraps a Handler. It may stop an interaction from reaching
+// the callback by returning without calling next.
+type Middleware func(next Handler) Handler
+
+// chain wraps h in mw, the first of which runs first
+func chain(h Handler, mw []Middleware) Handler {
+	for i := len(mw) - 1; i >= 0; i-- {
+		h = mw[i](h)
+	}
+	return h
+}
+
+// Recover stops a callback's panic from taking the bot down, logging it
+// instead
+func Recover(l bot.Logger) Middleware {
+	return func(next Handler) Handler {
+		return func(i Interaction) (out interface{}) {
+			defer func() {
+				if err := recover(); err != nil {
+					l.Errorf("slack/action: %s handler for %q panicked: %v\n%s", i.Type, i.ID, err, debug.Stack())
+					out = nil
+				}
+			}()
+			return next(i)
+		}
+	}
+}
+
+// Timing logs how long each callback took
+func Timing(l bot.Logger) Middleware {
+	return func(next Handler) Handler {
+		return func(i Interaction) interface{} {
+			start := time.Now()
+			defer func() {
+				l.Debugf("slack/action: %s handler for %q took %s\n", i.Type, i.ID, time.Since(start))
+			}()
+			return next(i)
+		}
+	}
+}
+
+// AllowUsers only lets interactions from the given user IDs through
+func AllowUsers(ids ...string) Middleware {
+	return allow(ids, func(i Interaction) string { return i.UserID })
+}
+
+// AllowChannels only lets interactions in the given channel IDs through.
+// Interactions outside of a channel, such as in modals, are stopped too.
+func AllowChannels(ids ...string) Middleware {
+	return allow(ids, func(i Interaction) string { return i.ChannelID })
+}
+
+func allow(ids []string, key func(Interaction) string) Middleware {
+	allowed := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		allowed[id] = true
+	}
+
+	return func(next Handler) Handler {
+		return func(i Interaction) interface{} {
+			if !allowed[key(i)] {
+				return nil
+			}
+			return next(i)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package action
+
+import (
+	"testing"
+
+	"github.com/botopolis/bot/mock"
+	adapter "github.com/botopolis/slack"
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+// trace is middleware recording that it ran
+func trace(ran *[]string, name string) Middleware {
+	return func(next Handler) Handler {
+		return func(i Interaction) interface{} {
+			*ran = append(*ran, name)
+			return next(i)
+		}
+	}
+}
+
+func TestRegistry_middleware(t *testing.T) {
+	var ran []string
+	r := registry{}
+	r.Use(trace(&ran, "global"))
+	r.AddWithResponse("approve", func(cb slack.AttachmentActionCallback) *Response {
+		ran = append(ran, "callback")
+		return &Response{Message: &Message{Text: "Approved"}}
+	}, trace(&ran, "first"), trace(&ran, "second"))
+	r.Add("reject", func(slack.AttachmentActionCallback) { ran = append(ran, "reject") })
+
+	resp := r.Run(slack.AttachmentActionCallback{CallbackID: "approve"})
+	assert.Equal(t, "Approved", resp.Message.Text)
+	assert.Equal(t, []string{"global", "first", "second", "callback"}, ran)
+
+	ran = nil
+	r.Run(slack.AttachmentActionCallback{CallbackID: "reject"})
+	assert.Equal(t, []string{"global", "reject"}, ran)
+}
+
+func TestRegistry_middlewareInteraction(t *testing.T) {
+	var seen Interaction
+	r := registry{}
+	r.Use(func(next Handler) Handler {
+		return func(i Interaction) interface{} {
+			seen = i
+			return next(i)
+		}
+	})
+	r.AddBlockAction("approve", func(BlockActionCallback, BlockAction) {})
+	r.AddOptions("team", func(OptionsRequest) Options {
+		return Options{Options: []adapter.Option{{Value: "sre"}}}
+	})
+
+	cb := BlockActionCallback{
+		User:      slack.User{ID: "U1"},
+		Container: Container{ChannelID: "C1"},
+		Actions:   []BlockAction{{ActionID: "approve", BlockID: "b1"}},
+	}
+	r.RunBlockActions(cb)
+	assert.Equal(t, "block_actions", seen.Type)
+	assert.Equal(t, "approve", seen.ID)
+	assert.Equal(t, "U1", seen.UserID)
+	assert.Equal(t, "C1", seen.ChannelID)
+	assert.Equal(t, "b1", seen.Action.BlockID)
+	assert.Equal(t, cb, seen.Payload)
+
+	opts := r.RunOptions(OptionsRequest{Type: "block_suggestion", ActionID: "team"})
+	assert.Equal(t, "block_suggestion", seen.Type)
+	assert.Len(t, opts.Options, 1)
+}
+
+func TestRecover(t *testing.T) {
+	var logged []mock.Level
+	l := mock.NewLogger()
+	l.WritefFunc = func(level mock.Level, _ string, _ ...interface{}) { logged = append(logged, level) }
+
+	r := registry{}
+	r.Use(Recover(l))
+	r.AddView("incident", func(ViewSubmissionCallback) *ViewResponse { panic("boom") })
+
+	cb := ViewSubmissionCallback{}
+	cb.View.CallbackID = "incident"
+	assert.NotPanics(t, func() { assert.Nil(t, r.RunView(cb)) })
+	assert.Equal(t, []mock.Level{mock.ErrorLevel}, logged)
+}
+
+func TestTiming(t *testing.T) {
+	var logged []interface{}
+	l := mock.NewLogger()
+	l.WritefFunc = func(_ mock.Level, _ string, v ...interface{}) { logged = v }
+
+	r := registry{}
+	r.AddShortcut("deploy", func(ShortcutCallback) {}, Timing(l))
+	r.RunShortcut(ShortcutCallback{Type: "shortcut", CallbackID: "deploy"})
+
+	if assert.Len(t, logged, 3) {
+		assert.Equal(t, "shortcut", logged[0])
+		assert.Equal(t, "deploy", logged[1])
+	}
+}
+
+func TestAllowUsers(t *testing.T) {
+	var ran []string
+	r := registry{}
+	r.Add("deploy", func(cb slack.AttachmentActionCallback) { ran = append(ran, cb.User.ID) }, AllowUsers("U1", "U2"))
+
+	for _, id := range []string{"U1", "U3", "U2", ""} {
+		r.Run(slack.AttachmentActionCallback{CallbackID: "deploy", User: slack.User{ID: id}})
+	}
+	assert.Equal(t, []string{"U1", "U2"}, ran)
+}
+
+func TestAllowChannels(t *testing.T) {
+	var ran []string
+	r := registry{}
+	r.Use(AllowChannels("C1"))
+	r.AddBlockAction("approve", func(cb BlockActionCallback, _ BlockAction) { ran = append(ran, cb.Container.ChannelID) })
+	r.AddViewClosed("incident", func(ViewClosedCallback) { ran = append(ran, "view") })
+
+	for _, id := range []string{"C1", "C2"} {
+		r.RunBlockActions(BlockActionCallback{
+			Container: Container{ChannelID: id},
+			Actions:   []BlockAction{{ActionID: "approve"}},
+		})
+	}
+	cb := ViewClosedCallback{}
+	cb.View.CallbackID = "incident"
+	r.RunViewClosed(cb)
+
+	assert.Equal(t, []string{"C1"}, ran)
+}
@@ -0,0 +1,118 @@
+package action
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	adapter "github.com/botopolis/slack"
+	"github.com/nlopes/slack"
+)
+
+// OptionsRequest is sent by Slack to load the options of an external
+// select menu as the user types
+type OptionsRequest struct {
+	// Type is block_suggestion for Block Kit menus, or dialog_suggestion
+	// for legacy dialogs
+	Type      string     `json:"type"`
+	Team      slack.Team `json:"team"`
+	User      slack.User `json:"user"`
+	Container Container  `json:"container"`
+	View      ViewState  `json:"view"`
+	// ActionID and BlockID identify a Block Kit menu
+	ActionID string `json:"action_id"`
+	BlockID  string `json:"block_id"`
+	// CallbackID and Name identify a dialog's menu
+	CallbackID string `json:"callback_id"`
+	Name       string `json:"name"`
+	// Value is what the user has typed so far
+	Value string `json:"value"`
+}
+
+// Options are the choices to show in an external select menu. Give
+// either Options or OptionGroups.
+type Options struct {
+	Options      []adapter.Option
+	OptionGroups []OptionGroup
+}
+
+// OptionGroup is a labelled group of Options
+type OptionGroup struct {
+	Label   string
+	Options []adapter.Option
+}
+
+// body builds the response for a block_suggestion or dialog_suggestion
+func (o Options) body(requestType string) interface{} {
+	if requestType != "dialog_suggestion" {
+		type group struct {
+			Label   *adapter.Text    `json:"label"`
+			Options []adapter.Option `json:"options"`
+		}
+		if len(o.OptionGroups) > 0 {
+			groups := make([]group, len(o.OptionGroups))
+			for i, g := range o.OptionGroups {
+				groups[i] = group{adapter.PlainText(g.Label), g.Options}
+			}
+			return map[string]interface{}{"option_groups": groups}
+		}
+		options := o.Options
+		if options == nil {
+			options = []adapter.Option{}
+		}
+		return map[string]interface{}{"options": options}
+	}
+
+	// Dialogs have their own, older, format
+	type option struct {
+		Label string `json:"label"`
+		Value string `json:"value"`
+	}
+	type group struct {
+		Label   string   `json:"label"`
+		Options []option `json:"options"`
+	}
+	convert := func(in []adapter.Option) []option {
+		out := make([]option, len(in))
+		for i, o := range in {
+			out[i] = option{o.Text, o.Value}
+		}
+		return out
+	}
+	if len(o.OptionGroups) > 0 {
+		groups := make([]group, len(o.OptionGroups))
+		for i, g := range o.OptionGroups {
+			groups[i] = group{g.Label, convert(g.Options)}
+		}
+		return map[string]interface{}{"option_groups": groups}
+	}
+	return map[string]interface{}{"options": convert(o.Options)}
+}
+
+// optionsKey is what option providers are registered with: the action ID
+// for Block Kit menus, or the element name for dialogs
+func (r OptionsRequest) optionsKey() string {
+	if r.Type == "dialog_suggestion" {
+		return r.Name
+	}
+	return r.ActionID
+}
+
+// respondOptions runs the options provider and sends back the options.
+// If it takes longer than the deadline, no options are shown.
+func (p Plugin) respondOptions(w http.ResponseWriter, req OptionsRequest) {
+	done := make(chan Options, 1)
+	go func() { done <- p.RunOptions(req) }()
+
+	var opts Options
+	select {
+	case opts = <-done:
+	case <-time.After(p.deadline()):
+		p.logger.Errorf("slack/action: Options for %s took longer than %s to load\n", req.optionsKey(), p.deadline())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(opts.body(req.Type)); err != nil {
+		p.logger.Errorf("slack/action: Unable to send options for %s: %v\n", req.optionsKey(), err)
+	}
+}
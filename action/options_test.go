@@ -0,0 +1,69 @@
+package action
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	adapter "github.com/botopolis/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhook_blockSuggestion(t *testing.T) {
+	p := Plugin{SigningSecret: signingSecret, registry: &registry{}, logger: logger}
+	p.AddOptions("service", func(req OptionsRequest) Options {
+		assert.Equal(t, "pay", req.Value)
+		return Options{Options: []adapter.Option{{Text: "Payments", Value: "payments"}}}
+	})
+
+	recorder := httptest.NewRecorder()
+	p.webhook(recorder, signedRequest(`{"type":"block_suggestion","action_id":"service","value":"pay"}`))
+	assert.JSONEq(t, `{"options":[{"text":{"type":"plain_text","text":"Payments","emoji":true},"value":"payments"}]}`, recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	p.webhook(recorder, signedRequest(`{"type":"block_suggestion","action_id":"unknown"}`))
+	assert.JSONEq(t, `{"options":[]}`, recorder.Body.String())
+}
+
+func TestWebhook_dialogSuggestion(t *testing.T) {
+	p := Plugin{SigningSecret: signingSecret, registry: &registry{}, logger: logger}
+	p.AddOptions("service", func(req OptionsRequest) Options {
+		return Options{OptionGroups: []OptionGroup{{
+			Label:   "Core",
+			Options: []adapter.Option{{Text: "Payments", Value: "payments"}},
+		}}}
+	})
+
+	recorder := httptest.NewRecorder()
+	p.webhook(recorder, signedRequest(`{"type":"dialog_suggestion","callback_id":"incident","name":"service"}`))
+	assert.JSONEq(t, `{"option_groups":[{"label":"Core","options":[{"label":"Payments","value":"payments"}]}]}`, recorder.Body.String())
+}
+
+func TestWebhook_optionsSlow(t *testing.T) {
+	release := make(chan bool)
+	defer close(release)
+	p := Plugin{SigningSecret: signingSecret, registry: &registry{}, logger: logger, Deadline: time.Millisecond}
+	p.AddOptions("service", func(OptionsRequest) Options {
+		<-release
+		return Options{}
+	})
+
+	recorder := httptest.NewRecorder()
+	p.webhook(recorder, signedRequest(`{"type":"block_suggestion","action_id":"service"}`))
+	assert.JSONEq(t, `{"options":[]}`, recorder.Body.String())
+}
+
+func TestOptions_groups(t *testing.T) {
+	opts := Options{OptionGroups: []OptionGroup{{
+		Label:   "Core",
+		Options: []adapter.Option{{Text: "Payments", Value: "payments"}},
+	}}}
+
+	b, err := json.Marshal(opts.body("block_suggestion"))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"option_groups":[{
+		"label": {"type": "plain_text", "text": "Core", "emoji": true},
+		"options": [{"text": {"type": "plain_text", "text": "Payments", "emoji": true}, "value": "payments"}]
+	}]}`, string(b))
+}
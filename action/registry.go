@@ -7,31 +7,232 @@ import (
 )
 
 type registry struct {
-	once      sync.Once
-	callbacks map[string]func(slack.AttachmentActionCallback)
-	mu        sync.Mutex
+	once         sync.Once
+	middleware   []Middleware
+	callbacks    map[string]Handler
+	blockActions map[string]Handler
+	views        map[string]Handler
+	viewsClosed  map[string]Handler
+	options      map[string]Handler
+	shortcuts    map[string]Handler
+	responders   map[string]*Responder
+	mu           sync.Mutex
 }
 
 func (r *registry) init() {
 	r.once.Do(func() {
-		r.callbacks = make(map[string]func(slack.AttachmentActionCallback))
+		r.callbacks = make(map[string]Handler)
+		r.blockActions = make(map[string]Handler)
+		r.views = make(map[string]Handler)
+		r.viewsClosed = make(map[string]Handler)
+		r.options = make(map[string]Handler)
+		r.shortcuts = make(map[string]Handler)
+		r.responders = make(map[string]*Responder)
 	})
 }
 
-// Add registers a callback for the given callbackID
-func (r *registry) Add(callbackID string, fn func(slack.AttachmentActionCallback)) {
+// Use adds middleware run around every callback, outside of the
+// middleware given when registering it
+func (r *registry) Use(mw ...Middleware) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	r.init()
-	r.callbacks[callbackID] = fn
+	r.middleware = append(r.middleware, mw...)
 }
 
-// Run runs the callback for the slack action
-func (r *registry) Run(cb slack.AttachmentActionCallback) {
+// add registers h, wrapped in mw, under key in handlers
+func (r *registry) add(handlers *map[string]Handler, key string, h Handler, mw []Middleware) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	r.init()
-	if fn, ok := r.callbacks[cb.CallbackID]; ok {
+	(*handlers)[key] = chain(h, mw)
+}
+
+// lookup returns the handler registered under key in handlers, wrapped in
+// the global middleware
+func (r *registry) lookup(handlers *map[string]Handler, key string) (Handler, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h, ok := (*handlers)[key]
+	if !ok {
+		return nil, false
+	}
+	return chain(h, r.middleware), true
+}
+
+// Add registers a callback for the given callbackID
+func (r *registry) Add(callbackID string, fn func(slack.AttachmentActionCallback), mw ...Middleware) {
+	r.AddWithResponse(callbackID, func(cb slack.AttachmentActionCallback) *Response {
 		fn(cb)
+		return nil
+	}, mw...)
+}
+
+// AddWithResponse registers a callback for the given callbackID, which
+// answers with a Response (see Plugin.Deadline)
+func (r *registry) AddWithResponse(callbackID string, fn func(slack.AttachmentActionCallback) *Response, mw ...Middleware) {
+	r.add(&r.callbacks, callbackID, func(i Interaction) interface{} {
+		return fn(i.Payload.(slack.AttachmentActionCallback))
+	}, mw)
+}
+
+// Run runs the callback for the slack action, returning its response
+func (r *registry) Run(cb slack.AttachmentActionCallback) *Response {
+	h, ok := r.lookup(&r.callbacks, cb.CallbackID)
+	if !ok {
+		return nil
+	}
+	resp, _ := h(Interaction{
+		Type:      "interactive_message",
+		ID:        cb.CallbackID,
+		UserID:    cb.User.ID,
+		ChannelID: cb.Channel.ID,
+		Payload:   cb,
+	}).(*Response)
+	return resp
+}
+
+// AddBlockAction registers a callback for Block Kit elements with the
+// given actionID, in whichever block they are
+func (r *registry) AddBlockAction(actionID string, fn func(BlockActionCallback, BlockAction), mw ...Middleware) {
+	r.AddBlock("", actionID, fn, mw...)
+}
+
+// AddBlock registers a callback for the Block Kit element with the given
+// actionID in the block with the given blockID. It takes precedence over
+// callbacks registered with AddBlockAction.
+func (r *registry) AddBlock(blockID, actionID string, fn func(BlockActionCallback, BlockAction), mw ...Middleware) {
+	r.add(&r.blockActions, blockKey(blockID, actionID), func(i Interaction) interface{} {
+		fn(i.Payload.(BlockActionCallback), i.Action)
+		return nil
+	}, mw)
+}
+
+// RunBlockActions runs the callback for each action in the payload
+func (r *registry) RunBlockActions(cb BlockActionCallback) {
+	channelID := cb.Channel.ID
+	if channelID == "" {
+		channelID = cb.Container.ChannelID
+	}
+
+	for _, a := range cb.Actions {
+		if h, ok := r.blockAction(a); ok {
+			h(Interaction{
+				Type:      "block_actions",
+				ID:        a.ActionID,
+				UserID:    cb.User.ID,
+				ChannelID: channelID,
+				Payload:   cb,
+				Action:    a,
+			})
+		}
+	}
+}
+
+func (r *registry) blockAction(a BlockAction) (Handler, bool) {
+	if h, ok := r.lookup(&r.blockActions, blockKey(a.BlockID, a.ActionID)); ok {
+		return h, true
 	}
+	return r.lookup(&r.blockActions, blockKey("", a.ActionID))
 }
+
+// AddView registers a callback for submissions of modals with the given
+// callbackID. What it returns is sent back to Slack (see ViewResponse).
+func (r *registry) AddView(callbackID string, fn func(ViewSubmissionCallback) *ViewResponse, mw ...Middleware) {
+	r.add(&r.views, callbackID, func(i Interaction) interface{} {
+		return fn(i.Payload.(ViewSubmissionCallback))
+	}, mw)
+}
+
+// AddViewClosed registers a callback for when modals with the given
+// callbackID are closed (see View.NotifyOnClose)
+func (r *registry) AddViewClosed(callbackID string, fn func(ViewClosedCallback), mw ...Middleware) {
+	r.add(&r.viewsClosed, callbackID, func(i Interaction) interface{} {
+		fn(i.Payload.(ViewClosedCallback))
+		return nil
+	}, mw)
+}
+
+// RunView runs the callback for the submitted modal
+func (r *registry) RunView(cb ViewSubmissionCallback) *ViewResponse {
+	h, ok := r.lookup(&r.views, cb.View.CallbackID)
+	if !ok {
+		return nil
+	}
+	resp, _ := h(Interaction{
+		Type:    "view_submission",
+		ID:      cb.View.CallbackID,
+		UserID:  cb.User.ID,
+		Payload: cb,
+	}).(*ViewResponse)
+	return resp
+}
+
+// RunViewClosed runs the callback for the closed modal
+func (r *registry) RunViewClosed(cb ViewClosedCallback) {
+	if h, ok := r.lookup(&r.viewsClosed, cb.View.CallbackID); ok {
+		h(Interaction{
+			Type:    "view_closed",
+			ID:      cb.View.CallbackID,
+			UserID:  cb.User.ID,
+			Payload: cb,
+		})
+	}
+}
+
+// AddOptions registers a provider of options for external select menus
+// with the given actionID. For dialogs, use the element's name instead.
+func (r *registry) AddOptions(actionID string, fn func(OptionsRequest) Options, mw ...Middleware) {
+	r.add(&r.options, actionID, func(i Interaction) interface{} {
+		return fn(i.Payload.(OptionsRequest))
+	}, mw)
+}
+
+// RunOptions runs the options provider for the select menu
+func (r *registry) RunOptions(req OptionsRequest) Options {
+	h, ok := r.lookup(&r.options, req.optionsKey())
+	if !ok {
+		return Options{}
+	}
+	opts, _ := h(Interaction{
+		Type:      req.Type,
+		ID:        req.optionsKey(),
+		UserID:    req.User.ID,
+		ChannelID: req.Container.ChannelID,
+		Payload:   req,
+	}).(Options)
+	return opts
+}
+
+// AddShortcut registers a callback for the global or message shortcut
+// with the given callbackID
+func (r *registry) AddShortcut(callbackID string, fn func(ShortcutCallback), mw ...Middleware) {
+	r.add(&r.shortcuts, callbackID, func(i Interaction) interface{} {
+		fn(i.Payload.(ShortcutCallback))
+		return nil
+	}, mw)
+}
+
+// RunShortcut runs the callback for the shortcut, reporting whether there
+// was one
+func (r *registry) RunShortcut(cb ShortcutCallback) bool {
+	h, ok := r.lookup(&r.shortcuts, cb.CallbackID)
+	if ok {
+		h(Interaction{
+			Type:      cb.Type,
+			ID:        cb.CallbackID,
+			UserID:    cb.User.ID,
+			ChannelID: cb.Channel.ID,
+			Payload:   cb,
+		})
+	}
+	return ok
+}
+
+func (r *registry) hasShortcut(callbackID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.shortcuts[callbackID]
+	return ok
+}
+
+func blockKey(blockID, actionID string) string { return blockID + "/" + actionID }
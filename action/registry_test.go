@@ -18,3 +18,18 @@ func TestRegistry(t *testing.T) {
 
 	assert.Equal(t, 1, counter)
 }
+
+func TestRegistry_blockActions(t *testing.T) {
+	var ran []string
+	r := registry{}
+	r.AddBlockAction("approve", func(_ BlockActionCallback, a BlockAction) { ran = append(ran, "any:"+a.BlockID) })
+	r.AddBlock("b2", "approve", func(_ BlockActionCallback, a BlockAction) { ran = append(ran, "b2") })
+
+	r.RunBlockActions(BlockActionCallback{Actions: []BlockAction{
+		{ActionID: "approve", BlockID: "b1"},
+		{ActionID: "approve", BlockID: "b2"},
+		{ActionID: "reject", BlockID: "b1"},
+	}})
+
+	assert.Equal(t, []string{"any:b1", "b2"}, ran)
+}
@@ -0,0 +1,200 @@
+package action
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	adapter "github.com/botopolis/slack"
+	"github.com/nlopes/slack"
+)
+
+// Slack accepts up to responseUses posts to a response_url within
+// responseTTL of the interaction.
+const (
+	responseUses = 5
+	responseTTL  = 30 * time.Minute
+)
+
+// Posts to a response_url which fail are retried this many times
+const responseRetries = 3
+
+var (
+	// ErrResponseExpired is returned when responding more than 30 minutes
+	// after the interaction
+	ErrResponseExpired = errors.New("slack/action: response_url has expired")
+	// ErrResponseLimit is returned when responding more than 5 times to
+	// the same interaction
+	ErrResponseLimit = errors.New("slack/action: response_url has been used 5 times")
+)
+
+// now and sleep are swapped out in tests
+var (
+	now   = time.Now
+	sleep = time.Sleep
+)
+
+// Message is sent to a response_url
+type Message struct {
+	Text        string
+	Blocks      []adapter.Block
+	Attachments []slack.Attachment
+}
+
+// Responder posts messages to an interaction's response_url, keeping
+// within the limits Slack puts on it. Get one with Plugin.Responder.
+type Responder struct {
+	url     string
+	expires time.Time
+
+	mu   sync.Mutex
+	uses int
+}
+
+func newResponder(url string) *Responder {
+	return &Responder{url: url, expires: now().Add(responseTTL)}
+}
+
+// Replace replaces the message the interaction happened in
+func (r *Responder) Replace(m Message) error {
+	return r.post(responseBody{Message: m, ReplaceOriginal: true})
+}
+
+// Ephemeral posts a message only the user who interacted can see
+func (r *Responder) Ephemeral(m Message) error {
+	return r.post(responseBody{Message: m, ResponseType: "ephemeral"})
+}
+
+// InChannel posts a message everyone in the channel can see
+func (r *Responder) InChannel(m Message) error {
+	return r.post(responseBody{Message: m, ResponseType: "in_channel"})
+}
+
+// Delete deletes the message the interaction happened in
+func (r *Responder) Delete() error {
+	return r.post(responseBody{DeleteOriginal: true})
+}
+
+type responseBody struct {
+	Message
+	ResponseType    string
+	ReplaceOriginal bool
+	DeleteOriginal  bool
+}
+
+// MarshalJSON builds the response_url payload
+func (b responseBody) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Text            string             `json:"text,omitempty"`
+		Blocks          []adapter.Block    `json:"blocks,omitempty"`
+		Attachments     []slack.Attachment `json:"attachments,omitempty"`
+		ResponseType    string             `json:"response_type,omitempty"`
+		ReplaceOriginal bool               `json:"replace_original,omitempty"`
+		DeleteOriginal  bool               `json:"delete_original,omitempty"`
+	}{b.Text, b.Blocks, b.Attachments, b.ResponseType, b.ReplaceOriginal, b.DeleteOriginal})
+}
+
+// use counts a post against the response_url's limits
+func (r *Responder) use() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if now().After(r.expires) {
+		return ErrResponseExpired
+	}
+	if r.uses >= responseUses {
+		return ErrResponseLimit
+	}
+	r.uses++
+	return nil
+}
+
+// post sends the body, retrying when Slack can't be reached or fails
+func (r *Responder) post(body responseBody) error {
+	if r.url == "" {
+		return errors.New("slack/action: No response_url to respond to")
+	}
+	if err := r.use(); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	wait := time.Second
+	for attempt := 0; ; attempt++ {
+		err = r.send(b)
+		if err == nil || attempt == responseRetries {
+			return err
+		}
+		if _, ok := err.(permanentError); ok {
+			return err
+		}
+		if rl, ok := err.(*slack.RateLimitedError); ok {
+			sleep(rl.RetryAfter)
+		} else {
+			sleep(wait)
+			wait *= 2
+		}
+	}
+}
+
+// permanentError is a response which retrying won't fix
+type permanentError struct{ error }
+
+func (r *Responder) send(b []byte) error {
+	resp, err := http.Post(r.url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests:
+		after, err := time.ParseDuration(resp.Header.Get("Retry-After") + "s")
+		if err != nil {
+			after = time.Second
+		}
+		return &slack.RateLimitedError{RetryAfter: after}
+	case resp.StatusCode >= 500:
+		return fmt.Errorf("slack/action: response_url returned %s", resp.Status)
+	default:
+		return permanentError{fmt.Errorf("slack/action: response_url returned %s", resp.Status)}
+	}
+}
+
+// Responder returns the Responder for an interaction's response_url. The
+// limits on its use are counted from when the interaction was received,
+// across every Responder returned for it.
+func (r *registry) Responder(responseURL string) *Responder {
+	if responseURL == "" {
+		return newResponder("")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.init()
+
+	if res, ok := r.responders[responseURL]; ok {
+		return res
+	}
+
+	// Forget about response_urls which can no longer be used
+	for url, res := range r.responders {
+		if now().After(res.expires) {
+			delete(r.responders, url)
+		}
+	}
+
+	res := newResponder(responseURL)
+	r.responders[responseURL] = res
+	return res
+}
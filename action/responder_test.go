@@ -0,0 +1,93 @@
+package action
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponder(t *testing.T) {
+	var bodies []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+	}))
+	defer server.Close()
+
+	r := registry{}
+	res := r.Responder(server.URL)
+	assert.True(t, res == r.Responder(server.URL))
+
+	assert.NoError(t, res.Replace(Message{Text: "replaced"}))
+	assert.NoError(t, res.Ephemeral(Message{Text: "psst"}))
+	assert.NoError(t, res.InChannel(Message{Text: "hear ye"}))
+	assert.NoError(t, res.Delete())
+	assert.Equal(t, []map[string]interface{}{
+		{"text": "replaced", "replace_original": true},
+		{"text": "psst", "response_type": "ephemeral"},
+		{"text": "hear ye", "response_type": "in_channel"},
+		{"delete_original": true},
+	}, bodies)
+
+	assert.NoError(t, res.Ephemeral(Message{Text: "fifth"}))
+	assert.Equal(t, ErrResponseLimit, res.Ephemeral(Message{Text: "sixth"}))
+	assert.Len(t, bodies, 5)
+}
+
+func TestResponder_expired(t *testing.T) {
+	start := time.Now()
+	now = func() time.Time { return start }
+	defer func() { now = time.Now }()
+
+	r := registry{}
+	res := r.Responder("http://example.com")
+	now = func() time.Time { return start.Add(31 * time.Minute) }
+	assert.Equal(t, ErrResponseExpired, res.Delete())
+
+	// Expired responders are forgotten
+	r.Responder("http://example.com/other")
+	_, ok := r.responders["http://example.com"]
+	assert.False(t, ok)
+}
+
+func TestResponder_retry(t *testing.T) {
+	var waited []time.Duration
+	sleep = func(d time.Duration) { waited = append(waited, d) }
+	defer func() { sleep = time.Sleep }()
+
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch calls {
+		case 1:
+			w.WriteHeader(http.StatusBadGateway)
+		case 2:
+			w.Header().Set("Retry-After", "3")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+
+	res := newResponder(server.URL)
+	assert.NoError(t, res.Replace(Message{Text: "eventually"}))
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, []time.Duration{time.Second, 3 * time.Second}, waited)
+	assert.Equal(t, 1, res.uses)
+}
+
+func TestResponder_permanentError(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	assert.Error(t, newResponder(server.URL).Delete())
+	assert.Equal(t, 1, calls)
+}
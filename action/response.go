@@ -0,0 +1,38 @@
+package action
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// Response is what a callback answers an interactive message or dialog
+// submission with. Returning nil leaves things as they are.
+type Response struct {
+	// Message replaces the message the interaction happened in
+	Message *Message
+	// Errors keeps a dialog open, showing errors keyed by element name
+	Errors map[string]string
+}
+
+// MarshalJSON builds the response body
+func (r Response) MarshalJSON() ([]byte, error) {
+	if len(r.Errors) > 0 {
+		type dialogError struct {
+			Name  string `json:"name"`
+			Error string `json:"error"`
+		}
+		errors := make([]dialogError, 0, len(r.Errors))
+		for name, err := range r.Errors {
+			errors = append(errors, dialogError{name, err})
+		}
+		sort.Slice(errors, func(i, j int) bool { return errors[i].Name < errors[j].Name })
+		return json.Marshal(struct {
+			Errors []dialogError `json:"errors"`
+		}{errors})
+	}
+
+	if r.Message != nil {
+		return json.Marshal(responseBody{Message: *r.Message, ReplaceOriginal: true})
+	}
+	return []byte("{}"), nil
+}
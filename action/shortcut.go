@@ -0,0 +1,20 @@
+package action
+
+import "github.com/nlopes/slack"
+
+// ShortcutCallback is the payload Slack sends when someone uses one of
+// the app's shortcuts. Use TriggerID to open a modal (see Plugin.OpenView).
+type ShortcutCallback struct {
+	// Type is shortcut for global shortcuts, from the lightning bolt menu,
+	// or message_action for message shortcuts, from a message's menu
+	Type       string     `json:"type"`
+	CallbackID string     `json:"callback_id"`
+	TriggerID  string     `json:"trigger_id"`
+	ActionTS   string     `json:"action_ts"`
+	Team       slack.Team `json:"team"`
+	User       slack.User `json:"user"`
+	// Channel, Message and ResponseURL are only set for message shortcuts
+	Channel     slack.Channel `json:"channel"`
+	Message     slack.Message `json:"message"`
+	ResponseURL string        `json:"response_url"`
+}
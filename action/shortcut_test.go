@@ -0,0 +1,52 @@
+package action
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhook_shortcut(t *testing.T) {
+	done := make(chan ShortcutCallback, 1)
+	p := Plugin{SigningSecret: signingSecret, registry: &registry{}, logger: logger}
+	p.AddShortcut("new_incident", func(cb ShortcutCallback) { done <- cb })
+
+	recorder := httptest.NewRecorder()
+	p.webhook(recorder, signedRequest(`{"type":"shortcut","callback_id":"new_incident","trigger_id":"T.1"}`))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	cb := <-done
+	assert.Equal(t, "shortcut", cb.Type)
+	assert.Equal(t, "T.1", cb.TriggerID)
+}
+
+func TestWebhook_messageAction(t *testing.T) {
+	done := make(chan ShortcutCallback, 1)
+	p := Plugin{SigningSecret: signingSecret, registry: &registry{}, logger: logger}
+	p.AddShortcut("file_bug", func(cb ShortcutCallback) { done <- cb })
+
+	p.webhook(httptest.NewRecorder(), signedRequest(`{
+		"type": "message_action",
+		"callback_id": "file_bug",
+		"trigger_id": "T.1",
+		"channel": {"id": "C1234", "name": "general"},
+		"message": {"type": "message", "text": "it's broken", "ts": "1.1"}
+	}`))
+
+	cb := <-done
+	assert.Equal(t, "C1234", cb.Channel.ID)
+	assert.Equal(t, "it's broken", cb.Message.Text)
+	assert.Equal(t, "1.1", cb.Message.Timestamp)
+}
+
+func TestWebhook_messageActionLegacy(t *testing.T) {
+	done := make(chan slack.AttachmentActionCallback, 1)
+	p := Plugin{SigningSecret: signingSecret, registry: &registry{}, logger: logger}
+	p.Add("file_bug", func(cb slack.AttachmentActionCallback) { done <- cb })
+
+	p.webhook(httptest.NewRecorder(), signedRequest(`{"type":"message_action","callback_id":"file_bug"}`))
+	assert.Equal(t, "file_bug", (<-done).CallbackID)
+}
@@ -0,0 +1,178 @@
+package action
+
+import (
+	"encoding/json"
+
+	adapter "github.com/botopolis/slack"
+	"github.com/botopolis/slack/internal/webapi"
+	"github.com/nlopes/slack"
+)
+
+// View is a modal to open, push or update. Use Input blocks to collect
+// values, which are given back in ViewSubmissionCallback.View.State.
+type View struct {
+	Title  string
+	Submit string
+	Close  string
+	Blocks []adapter.Block
+
+	// CallbackID is what view_submission and view_closed handlers are
+	// registered with
+	CallbackID      string
+	PrivateMetadata string
+	ExternalID      string
+	ClearOnClose    bool
+	// NotifyOnClose sends a view_closed payload when the user closes the modal
+	NotifyOnClose bool
+}
+
+// MarshalJSON builds the modal view payload
+func (v View) MarshalJSON() ([]byte, error) {
+	var submit, cancel *adapter.Text
+	if v.Submit != "" {
+		submit = adapter.PlainText(v.Submit)
+	}
+	if v.Close != "" {
+		cancel = adapter.PlainText(v.Close)
+	}
+
+	return json.Marshal(struct {
+		Type            string          `json:"type"`
+		Title           *adapter.Text   `json:"title"`
+		Submit          *adapter.Text   `json:"submit,omitempty"`
+		Close           *adapter.Text   `json:"close,omitempty"`
+		Blocks          []adapter.Block `json:"blocks"`
+		CallbackID      string          `json:"callback_id,omitempty"`
+		PrivateMetadata string          `json:"private_metadata,omitempty"`
+		ExternalID      string          `json:"external_id,omitempty"`
+		ClearOnClose    bool            `json:"clear_on_close,omitempty"`
+		NotifyOnClose   bool            `json:"notify_on_close,omitempty"`
+	}{
+		Type:            "modal",
+		Title:           adapter.PlainText(v.Title),
+		Submit:          submit,
+		Close:           cancel,
+		Blocks:          v.Blocks,
+		CallbackID:      v.CallbackID,
+		PrivateMetadata: v.PrivateMetadata,
+		ExternalID:      v.ExternalID,
+		ClearOnClose:    v.ClearOnClose,
+		NotifyOnClose:   v.NotifyOnClose,
+	})
+}
+
+// ViewState is a view as Slack knows it, with whatever the user has
+// entered so far
+type ViewState struct {
+	ID              string `json:"id"`
+	TeamID          string `json:"team_id"`
+	Type            string `json:"type"`
+	CallbackID      string `json:"callback_id"`
+	PrivateMetadata string `json:"private_metadata"`
+	ExternalID      string `json:"external_id"`
+	Hash            string `json:"hash"`
+	RootViewID      string `json:"root_view_id"`
+	PreviousViewID  string `json:"previous_view_id"`
+	State           struct {
+		// Values are keyed by block ID, then action ID
+		Values map[string]map[string]BlockAction `json:"values"`
+	} `json:"state"`
+}
+
+// Value returns what was entered in the element with actionID in the
+// block with blockID
+func (v ViewState) Value(blockID, actionID string) (BlockAction, bool) {
+	a, ok := v.State.Values[blockID][actionID]
+	return a, ok
+}
+
+// ViewSubmissionCallback is the payload Slack sends when a modal is
+// submitted
+type ViewSubmissionCallback struct {
+	Type      string     `json:"type"`
+	Team      slack.Team `json:"team"`
+	User      slack.User `json:"user"`
+	APIAppID  string     `json:"api_app_id"`
+	TriggerID string     `json:"trigger_id"`
+	View      ViewState  `json:"view"`
+}
+
+// ViewClosedCallback is the payload Slack sends when a modal opened with
+// NotifyOnClose is closed
+type ViewClosedCallback struct {
+	Type      string     `json:"type"`
+	Team      slack.Team `json:"team"`
+	User      slack.User `json:"user"`
+	APIAppID  string     `json:"api_app_id"`
+	View      ViewState  `json:"view"`
+	IsCleared bool       `json:"is_cleared"`
+}
+
+// ViewResponse tells Slack what to do with a submitted modal. Returning
+// nil from a view_submission handler closes it.
+type ViewResponse struct {
+	ResponseAction string            `json:"response_action"`
+	Errors         map[string]string `json:"errors,omitempty"`
+	View           *View             `json:"view,omitempty"`
+}
+
+// ViewErrors keeps the modal open, showing errors keyed by block ID
+func ViewErrors(errors map[string]string) *ViewResponse {
+	return &ViewResponse{ResponseAction: "errors", Errors: errors}
+}
+
+// ViewUpdate replaces the submitted modal with v
+func ViewUpdate(v View) *ViewResponse {
+	return &ViewResponse{ResponseAction: "update", View: &v}
+}
+
+// ViewPush shows v on top of the submitted modal
+func ViewPush(v View) *ViewResponse {
+	return &ViewResponse{ResponseAction: "push", View: &v}
+}
+
+// ViewClear closes all of the user's modals
+func ViewClear() *ViewResponse {
+	return &ViewResponse{ResponseAction: "clear"}
+}
+
+type viewResponse struct {
+	slack.SlackResponse
+	View ViewState `json:"view"`
+}
+
+// OpenView opens a modal in response to an interaction, using its
+// trigger ID. It requires Plugin.Token.
+func (p *Plugin) OpenView(triggerID string, v View) (ViewState, error) {
+	var resp viewResponse
+	err := webapi.JSON(p.Token, "views.open", map[string]interface{}{
+		"trigger_id": triggerID,
+		"view":       v,
+	}, &resp)
+	return resp.View, err
+}
+
+// PushView shows a modal on top of the one the interaction happened in.
+// It requires Plugin.Token.
+func (p *Plugin) PushView(triggerID string, v View) (ViewState, error) {
+	var resp viewResponse
+	err := webapi.JSON(p.Token, "views.push", map[string]interface{}{
+		"trigger_id": triggerID,
+		"view":       v,
+	}, &resp)
+	return resp.View, err
+}
+
+// UpdateView replaces an open modal. If hash is given (see ViewState.Hash)
+// the update fails when the view changed in the meantime. It requires
+// Plugin.Token.
+func (p *Plugin) UpdateView(viewID, hash string, v View) (ViewState, error) {
+	body := map[string]interface{}{"view_id": viewID, "view": v}
+	if hash != "" {
+		body["hash"] = hash
+	}
+
+	var resp viewResponse
+	err := webapi.JSON(p.Token, "views.update", body, &resp)
+	return resp.View, err
+}
@@ -0,0 +1,157 @@
+package action
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	adapter "github.com/botopolis/slack"
+	"github.com/botopolis/slack/internal/signing/signingtest"
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+const submission = `{
+	"type": "view_submission",
+	"user": {"id": "U1234", "name": "jean"},
+	"view": {
+		"id": "V1234",
+		"callback_id": "incident",
+		"state": {"values": {"summary": {"text": {"type": "plain_text_input", "value": "db down"}}}}
+	}
+}`
+
+// signedRequest builds a webhook request carrying payload, signed with
+// the test signing secret
+func signedRequest(payload string) *http.Request {
+	return signingtest.Request(signingSecret, "payload="+url.QueryEscape(payload))
+}
+
+func TestWebhook_viewSubmission(t *testing.T) {
+	p := Plugin{SigningSecret: signingSecret, registry: &registry{}, logger: logger}
+	p.AddView("incident", func(cb ViewSubmissionCallback) *ViewResponse {
+		summary, ok := cb.View.Value("summary", "text")
+		assert.True(t, ok)
+		assert.Equal(t, "db down", summary.Value)
+		assert.Equal(t, "jean", cb.User.Name)
+		return ViewErrors(map[string]string{"summary": "Too vague"})
+	})
+
+	recorder := httptest.NewRecorder()
+	p.webhook(recorder, signedRequest(submission))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"response_action":"errors","errors":{"summary":"Too vague"}}`, recorder.Body.String())
+}
+
+func TestWebhook_viewSubmissionSlow(t *testing.T) {
+	release := make(chan bool)
+	p := Plugin{SigningSecret: signingSecret, registry: &registry{}, logger: logger, Deadline: time.Millisecond}
+	p.AddView("incident", func(ViewSubmissionCallback) *ViewResponse {
+		<-release
+		return ViewClear()
+	})
+
+	recorder := httptest.NewRecorder()
+	p.webhook(recorder, signedRequest(submission))
+	close(release)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Empty(t, recorder.Body.String())
+}
+
+func TestWebhook_rotatedSecrets(t *testing.T) {
+	p := Plugin{registry: &registry{}, logger: logger}
+	p.SigningSecrets = []string{"new secret", signingSecret}
+	recorder := httptest.NewRecorder()
+	p.webhook(recorder, signedRequest(`{"type":"view_closed","view":{"callback_id":"incident"}}`))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	p.SecretsProvider = func() ([]string, error) { return []string{signingSecret}, nil }
+	p.SigningSecrets = nil
+	recorder = httptest.NewRecorder()
+	p.webhook(recorder, signedRequest(`{"type":"view_closed","view":{"callback_id":"incident"}}`))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	p.SecretsProvider = func() ([]string, error) { return []string{"new secret"}, nil }
+	recorder = httptest.NewRecorder()
+	p.webhook(recorder, signedRequest(`{"type":"view_closed","view":{"callback_id":"incident"}}`))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestWebhook_viewClosed(t *testing.T) {
+	done := make(chan ViewClosedCallback, 1)
+	p := Plugin{SigningSecret: signingSecret, registry: &registry{}, logger: logger}
+	p.AddViewClosed("incident", func(cb ViewClosedCallback) { done <- cb })
+
+	p.webhook(httptest.NewRecorder(), signedRequest(`{"type":"view_closed","is_cleared":true,"view":{"callback_id":"incident"}}`))
+	assert.True(t, (<-done).IsCleared)
+}
+
+func TestView_marshal(t *testing.T) {
+	b, err := json.Marshal(View{
+		Title:         "Incident",
+		Submit:        "Open",
+		CallbackID:    "incident",
+		NotifyOnClose: true,
+		Blocks: []adapter.Block{adapter.Input{
+			BlockID: "summary",
+			Label:   "Summary",
+			Element: adapter.PlainTextInput{ActionID: "text"},
+		}},
+	})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "modal",
+		"title": {"type": "plain_text", "text": "Incident", "emoji": true},
+		"submit": {"type": "plain_text", "text": "Open", "emoji": true},
+		"callback_id": "incident",
+		"notify_on_close": true,
+		"blocks": [{
+			"type": "input",
+			"block_id": "summary",
+			"label": {"type": "plain_text", "text": "Summary", "emoji": true},
+			"element": {"type": "plain_text_input", "action_id": "text"}
+		}]
+	}`, string(b))
+}
+
+func TestPlugin_views(t *testing.T) {
+	var requests []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer xoxb-1", r.Header.Get("Authorization"))
+
+		body := map[string]interface{}{"method": r.URL.Path}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		requests = append(requests, body)
+
+		w.Write([]byte(`{"ok":true,"view":{"id":"V1234","hash":"h1"}}`))
+	}))
+	defer server.Close()
+
+	api := slack.SLACK_API
+	slack.SLACK_API = server.URL + "/"
+	defer func() { slack.SLACK_API = api }()
+
+	p := Plugin{Token: "xoxb-1", registry: &registry{}}
+	v, err := p.OpenView("T.1", View{Title: "Incident"})
+	assert.NoError(t, err)
+	assert.Equal(t, "V1234", v.ID)
+	assert.Equal(t, "h1", v.Hash)
+
+	_, err = p.PushView("T.2", View{Title: "Details"})
+	assert.NoError(t, err)
+	_, err = p.UpdateView(v.ID, v.Hash, View{Title: "Incident"})
+	assert.NoError(t, err)
+
+	assert.Len(t, requests, 3)
+	assert.Equal(t, "/views.open", requests[0]["method"])
+	assert.Equal(t, "T.1", requests[0]["trigger_id"])
+	assert.Equal(t, "modal", requests[0]["view"].(map[string]interface{})["type"])
+	assert.Equal(t, "/views.push", requests[1]["method"])
+	assert.Equal(t, "T.2", requests[1]["trigger_id"])
+	assert.Equal(t, "/views.update", requests[2]["method"])
+	assert.Equal(t, "V1234", requests[2]["view_id"])
+	assert.Equal(t, "h1", requests[2]["hash"])
+}
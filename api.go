@@ -0,0 +1,62 @@
+package slack
+
+import (
+	"encoding/json"
+	"net/url"
+
+	"github.com/botopolis/bot"
+	"github.com/nlopes/slack"
+)
+
+// messageValues encodes a message's room, text and params for the web API
+// methods the slack client can't send blocks with
+func messageValues(m bot.Message) (url.Values, error) {
+	values := url.Values{
+		"channel": {m.Room},
+		"text":    {m.Text},
+		"as_user": {"true"},
+	}
+	thread := func(ts string, broadcast bool) {
+		if ts != "" {
+			values.Set("thread_ts", ts)
+			if broadcast {
+				values.Set("reply_broadcast", "true")
+			}
+		}
+	}
+
+	switch pm := m.Params.(type) {
+	case Thread:
+		thread(pm.Timestamp, pm.Broadcast)
+	case slack.PostMessageParameters:
+		if len(pm.Attachments) > 0 {
+			attachments, err := json.Marshal(pm.Attachments)
+			if err != nil {
+				return nil, err
+			}
+			values.Set("attachments", string(attachments))
+		}
+		thread(pm.ThreadTimestamp, pm.ReplyBroadcast)
+		if pm.LinkNames == 1 {
+			values.Set("link_names", "1")
+		}
+		if pm.Parse != "" {
+			values.Set("parse", pm.Parse)
+		}
+	case Blocks:
+		blocks, err := json.Marshal(pm.Blocks)
+		if err != nil {
+			return nil, err
+		}
+		values.Set("blocks", string(blocks))
+		thread(pm.ThreadTimestamp, pm.ReplyBroadcast)
+		if pm.UnfurlLinks {
+			values.Set("unfurl_links", "true")
+		}
+		if pm.UnfurlMedia {
+			values.Set("unfurl_media", "true")
+		}
+	}
+
+	return values, nil
+}
@@ -0,0 +1,38 @@
+package slack
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// backoff gives jittered, exponentially growing delays between attempts.
+// It's safe to reset from another goroutine than the one waiting.
+type backoff struct {
+	Min, Max time.Duration
+
+	mu       sync.Mutex
+	attempts uint
+}
+
+// Duration returns how long to wait before the next attempt: a random
+// time between Min and Min*2^attempts, capped at Max.
+func (b *backoff) Duration() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ceil := b.Min << b.attempts
+	if ceil > b.Max || ceil <= 0 {
+		ceil = b.Max
+	} else {
+		b.attempts++
+	}
+
+	return b.Min + time.Duration(rand.Int63n(int64(ceil-b.Min)+1))
+}
+
+// Reset starts over from Min after a successful attempt
+func (b *backoff) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.attempts = 0
+}
@@ -0,0 +1,389 @@
+package slack
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/botopolis/bot"
+	"github.com/botopolis/slack/internal/webapi"
+	"github.com/nlopes/slack"
+)
+
+// Blocks can be given as bot.Message.Params to send a Block Kit message.
+// bot.Message.Text is used for notifications; when it's empty, it is
+// derived from the text in the blocks.
+type Blocks struct {
+	Blocks []Block
+	// ThreadTimestamp posts the message in a thread
+	ThreadTimestamp string
+	// ReplyBroadcast also shows a threaded reply in the channel
+	ReplyBroadcast bool
+	// UnfurlLinks and UnfurlMedia show previews of links in the message
+	UnfurlLinks bool
+	UnfurlMedia bool
+}
+
+// Block is one of Section, Context, Divider, Actions, Header, Image or Input
+type Block interface{ block() }
+
+// Element is something which goes inside a block: Text, Button,
+// ImageElement, PlainTextInput, StaticSelect or ExternalSelect. Which
+// elements a block accepts is documented on the block.
+type Element interface{ element() }
+
+// Text is a text object, see Markdown and PlainText
+type Text struct {
+	Type     string `json:"type"`
+	Text     string `json:"text"`
+	Emoji    bool   `json:"emoji,omitempty"`
+	Verbatim bool   `json:"verbatim,omitempty"`
+}
+
+// Markdown is text formatted with Slack's mrkdwn
+func Markdown(text string) *Text { return &Text{Type: "mrkdwn", Text: text} }
+
+// PlainText is text shown as is, with emoji such as :wave: rendered
+func PlainText(text string) *Text { return &Text{Type: "plain_text", Text: text, Emoji: true} }
+
+// Section is a block of text, optionally laid out in two columns of
+// fields, with a Button or ImageElement as accessory.
+type Section struct {
+	BlockID   string  `json:"block_id,omitempty"`
+	Text      *Text   `json:"text,omitempty"`
+	Fields    []*Text `json:"fields,omitempty"`
+	Accessory Element `json:"accessory,omitempty"`
+}
+
+// Context is a block of small Text and ImageElements
+type Context struct {
+	BlockID  string    `json:"block_id,omitempty"`
+	Elements []Element `json:"elements"`
+}
+
+// Divider is a horizontal line
+type Divider struct {
+	BlockID string `json:"block_id,omitempty"`
+}
+
+// Actions is a block of Buttons and select menus
+type Actions struct {
+	BlockID  string    `json:"block_id,omitempty"`
+	Elements []Element `json:"elements"`
+}
+
+// Header is a block of large, bold, plain text
+type Header struct {
+	BlockID string `json:"block_id,omitempty"`
+	Text    string `json:"-"`
+}
+
+// Image is a block showing a single image
+type Image struct {
+	BlockID  string `json:"block_id,omitempty"`
+	ImageURL string `json:"image_url"`
+	AltText  string `json:"alt_text"`
+	Title    *Text  `json:"title,omitempty"`
+}
+
+// Input is a block collecting a value with a PlainTextInput or a select
+// menu. It can only be used in modal views.
+type Input struct {
+	BlockID  string  `json:"block_id,omitempty"`
+	Label    string  `json:"-"`
+	Element  Element `json:"element"`
+	Hint     string  `json:"-"`
+	Optional bool    `json:"optional,omitempty"`
+}
+
+// ImageElement is a small image shown in a Context or next to a Section
+type ImageElement struct {
+	ImageURL string `json:"image_url"`
+	AltText  string `json:"alt_text"`
+}
+
+// Button sends a block_actions interaction when clicked, or opens URL
+type Button struct {
+	ActionID string `json:"action_id,omitempty"`
+	Text     string `json:"-"`
+	Value    string `json:"value,omitempty"`
+	URL      string `json:"url,omitempty"`
+	// Style is "primary", "danger" or empty for the default
+	Style string `json:"style,omitempty"`
+}
+
+// PlainTextInput is a text field in an Input block
+type PlainTextInput struct {
+	ActionID     string `json:"action_id,omitempty"`
+	Placeholder  string `json:"-"`
+	InitialValue string `json:"initial_value,omitempty"`
+	Multiline    bool   `json:"multiline,omitempty"`
+	MinLength    int    `json:"min_length,omitempty"`
+	MaxLength    int    `json:"max_length,omitempty"`
+}
+
+// StaticSelect is a menu of Options
+type StaticSelect struct {
+	ActionID      string   `json:"action_id,omitempty"`
+	Placeholder   string   `json:"-"`
+	Options       []Option `json:"options"`
+	InitialOption *Option  `json:"initial_option,omitempty"`
+}
+
+// ExternalSelect is a menu whose Options are loaded from the app as the
+// user types (see the action package's Plugin.AddOptions)
+type ExternalSelect struct {
+	ActionID       string  `json:"action_id,omitempty"`
+	Placeholder    string  `json:"-"`
+	InitialOption  *Option `json:"initial_option,omitempty"`
+	MinQueryLength int     `json:"min_query_length,omitempty"`
+}
+
+// Option is a choice in a select menu
+type Option struct {
+	Text  string `json:"-"`
+	Value string `json:"value"`
+}
+
+func (Section) block() {}
+func (Context) block() {}
+func (Divider) block() {}
+func (Actions) block() {}
+func (Header) block()  {}
+func (Image) block()   {}
+func (Input) block()   {}
+
+func (*Text) element()          {}
+func (Button) element()         {}
+func (ImageElement) element()   {}
+func (PlainTextInput) element() {}
+func (StaticSelect) element()   {}
+func (ExternalSelect) element() {}
+
+// MarshalJSON adds the block type
+func (b Section) MarshalJSON() ([]byte, error) {
+	type section Section
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		section
+	}{"section", section(b)})
+}
+
+// MarshalJSON adds the block type
+func (b Context) MarshalJSON() ([]byte, error) {
+	type context Context
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		context
+	}{"context", context(b)})
+}
+
+// MarshalJSON adds the block type
+func (b Divider) MarshalJSON() ([]byte, error) {
+	type divider Divider
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		divider
+	}{"divider", divider(b)})
+}
+
+// MarshalJSON adds the block type
+func (b Actions) MarshalJSON() ([]byte, error) {
+	type actions Actions
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		actions
+	}{"actions", actions(b)})
+}
+
+// MarshalJSON adds the block type and wraps the text in a text object
+func (b Header) MarshalJSON() ([]byte, error) {
+	type header Header
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		Text *Text  `json:"text"`
+		header
+	}{"header", PlainText(b.Text), header(b)})
+}
+
+// MarshalJSON adds the block type
+func (b Image) MarshalJSON() ([]byte, error) {
+	type image Image
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		image
+	}{"image", image(b)})
+}
+
+// MarshalJSON adds the element type
+func (e ImageElement) MarshalJSON() ([]byte, error) {
+	type image ImageElement
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		image
+	}{"image", image(e)})
+}
+
+// MarshalJSON adds the element type and wraps the text in a text object
+func (e Button) MarshalJSON() ([]byte, error) {
+	type button Button
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		Text *Text  `json:"text"`
+		button
+	}{"button", PlainText(e.Text), button(e)})
+}
+
+// MarshalJSON adds the block type and wraps the label and hint in text
+// objects
+func (b Input) MarshalJSON() ([]byte, error) {
+	type input Input
+	var hint *Text
+	if b.Hint != "" {
+		hint = PlainText(b.Hint)
+	}
+	return json.Marshal(struct {
+		Type  string `json:"type"`
+		Label *Text  `json:"label"`
+		Hint  *Text  `json:"hint,omitempty"`
+		input
+	}{"input", PlainText(b.Label), hint, input(b)})
+}
+
+// MarshalJSON adds the element type and wraps the placeholder in a text
+// object
+func (e PlainTextInput) MarshalJSON() ([]byte, error) {
+	type textInput PlainTextInput
+	return json.Marshal(struct {
+		Type        string `json:"type"`
+		Placeholder *Text  `json:"placeholder,omitempty"`
+		textInput
+	}{"plain_text_input", placeholder(e.Placeholder), textInput(e)})
+}
+
+// MarshalJSON adds the element type and wraps the placeholder in a text
+// object
+func (e StaticSelect) MarshalJSON() ([]byte, error) {
+	type static StaticSelect
+	return json.Marshal(struct {
+		Type        string `json:"type"`
+		Placeholder *Text  `json:"placeholder,omitempty"`
+		static
+	}{"static_select", placeholder(e.Placeholder), static(e)})
+}
+
+// MarshalJSON adds the element type and wraps the placeholder in a text
+// object
+func (e ExternalSelect) MarshalJSON() ([]byte, error) {
+	type external ExternalSelect
+	return json.Marshal(struct {
+		Type        string `json:"type"`
+		Placeholder *Text  `json:"placeholder,omitempty"`
+		external
+	}{"external_select", placeholder(e.Placeholder), external(e)})
+}
+
+// MarshalJSON wraps the text in a text object
+func (o Option) MarshalJSON() ([]byte, error) {
+	type option Option
+	return json.Marshal(struct {
+		Text *Text `json:"text"`
+		option
+	}{PlainText(o.Text), option(o)})
+}
+
+// UnmarshalJSON reads the text out of its text object, as Slack sends
+// selected options back
+func (o *Option) UnmarshalJSON(b []byte) error {
+	type option Option
+	var v struct {
+		Text Text `json:"text"`
+		option
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*o = Option(v.option)
+	o.Text = v.Text.Text
+	return nil
+}
+
+func placeholder(text string) *Text {
+	if text == "" {
+		return nil
+	}
+	return PlainText(text)
+}
+
+// fallback collects the text in the blocks, one block per line, to show
+// in notifications
+func (b Blocks) fallback() string {
+	var lines []string
+	add := func(t *Text) {
+		if t != nil && t.Text != "" {
+			lines = append(lines, t.Text)
+		}
+	}
+
+	for _, block := range b.Blocks {
+		switch block := deref(block).(type) {
+		case Header:
+			add(PlainText(block.Text))
+		case Section:
+			add(block.Text)
+			for _, f := range block.Fields {
+				add(f)
+			}
+		case Context:
+			for _, e := range block.Elements {
+				if t, ok := e.(*Text); ok {
+					add(t)
+				}
+			}
+		case Image:
+			add(block.Title)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// deref returns the block a pointer points to, so that fallback handles
+// blocks given either way
+func deref(block Block) Block {
+	switch b := block.(type) {
+	case *Header:
+		if b != nil {
+			return *b
+		}
+	case *Section:
+		if b != nil {
+			return *b
+		}
+	case *Context:
+		if b != nil {
+			return *b
+		}
+	case *Image:
+		if b != nil {
+			return *b
+		}
+	}
+	return block
+}
+
+// postBlocks sends a Block Kit message with chat.postMessage, which the
+// slack client can't do yet.
+func (a *Adapter) postBlocks(room, text string, b Blocks) (MessageRef, error) {
+	values, err := messageValues(bot.Message{Room: room, Text: text, Params: b})
+	if err != nil {
+		return MessageRef{}, err
+	}
+
+	var resp struct {
+		slack.SlackResponse
+		Channel   string `json:"channel"`
+		Timestamp string `json:"ts"`
+	}
+	err = webapi.Form(a.token, "chat.postMessage", values, &resp)
+	return MessageRef{Channel: resp.Channel, Timestamp: resp.Timestamp}, err
+}
@@ -0,0 +1,102 @@
+package slack
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/botopolis/bot"
+	"github.com/stretchr/testify/assert"
+)
+
+var testBlocks = Blocks{Blocks: []Block{
+	Header{Text: "Status"},
+	Section{
+		Text:      Markdown("*All good*"),
+		Fields:    []*Text{Markdown("api: up"), Markdown("db: up")},
+		Accessory: Button{ActionID: "refresh", Text: "Refresh", Style: "primary"},
+	},
+	Divider{},
+	Context{Elements: []Element{
+		ImageElement{ImageURL: "https://example.com/ok.png", AltText: "ok"},
+		PlainText("checked just now"),
+	}},
+	Actions{Elements: []Element{Button{Text: "Docs", URL: "https://example.com"}}},
+}}
+
+func TestBlocks_marshal(t *testing.T) {
+	b, err := json.Marshal(testBlocks.Blocks)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"type": "header", "text": {"type": "plain_text", "text": "Status", "emoji": true}},
+		{
+			"type": "section",
+			"text": {"type": "mrkdwn", "text": "*All good*"},
+			"fields": [{"type": "mrkdwn", "text": "api: up"}, {"type": "mrkdwn", "text": "db: up"}],
+			"accessory": {
+				"type": "button",
+				"action_id": "refresh",
+				"text": {"type": "plain_text", "text": "Refresh", "emoji": true},
+				"style": "primary"
+			}
+		},
+		{"type": "divider"},
+		{"type": "context", "elements": [
+			{"type": "image", "image_url": "https://example.com/ok.png", "alt_text": "ok"},
+			{"type": "plain_text", "text": "checked just now", "emoji": true}
+		]},
+		{"type": "actions", "elements": [
+			{"type": "button", "text": {"type": "plain_text", "text": "Docs", "emoji": true}, "url": "https://example.com"}
+		]}
+	]`, string(b))
+}
+
+func TestBlocks_fallback(t *testing.T) {
+	assert.Equal(t, "Status\n*All good*\napi: up\ndb: up\nchecked just now", testBlocks.fallback())
+}
+
+func TestBlocks_fallbackPointers(t *testing.T) {
+	b := Blocks{Blocks: []Block{
+		&Header{Text: "Status"},
+		&Section{Text: Markdown("*All good*")},
+		&Context{Elements: []Element{PlainText("checked just now")}},
+		&Image{Title: PlainText("graph")},
+		(*Section)(nil),
+	}}
+	assert.Equal(t, "Status\n*All good*\nchecked just now\ngraph", b.fallback())
+}
+
+func TestOption_unmarshal(t *testing.T) {
+	var o Option
+	err := json.Unmarshal([]byte(`{"text": {"type": "plain_text", "text": "Payments"}, "value": "payments"}`), &o)
+	assert.NoError(t, err)
+	assert.Equal(t, Option{Text: "Payments", Value: "payments"}, o)
+}
+
+func TestAdapterSend_blocks(t *testing.T) {
+	var form map[string][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/chat.postMessage", r.URL.Path)
+		assert.Equal(t, "Bearer xoxb-1", r.Header.Get("Authorization"))
+		r.ParseForm()
+		form = r.PostForm
+		w.Write([]byte(`{"ok":true,"channel":"C1234","ts":"1.2"}`))
+	}))
+	defer server.Close()
+
+	defer useAPI(server.URL)()
+
+	a := New("xoxb-1")
+	a.Robot = &bot.Robot{}
+	params := testBlocks
+	params.ThreadTimestamp = "1.1"
+	assert.NoError(t, a.Send(bot.Message{Room: "C1234", Params: params}))
+
+	blocks, _ := json.Marshal(testBlocks.Blocks)
+	assert.Equal(t, "C1234", form["channel"][0])
+	assert.Equal(t, testBlocks.fallback(), form["text"][0])
+	assert.Equal(t, string(blocks), form["blocks"][0])
+	assert.Equal(t, "1.1", form["thread_ts"][0])
+	assert.Empty(t, form["reply_broadcast"])
+}
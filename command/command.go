@@ -0,0 +1,119 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/botopolis/bot"
+	adapter "github.com/botopolis/slack"
+	"github.com/botopolis/slack/internal/signing"
+	"github.com/nlopes/slack"
+)
+
+// responseTimeout is how long we wait for a handler before answering
+// Slack, which gives up after 3 seconds. Later responses are posted to
+// the command's response_url instead.
+var responseTimeout = 2500 * time.Millisecond
+
+// Plugin conforms to the botopolis/bot.Plugin interface
+type Plugin struct {
+	*registry
+	// Path at which our webhook sits.
+	Path string
+	// Signing secret to verify message comes from slack.
+	SigningSecret string
+	adapter.Verification
+
+	logger bot.Logger
+}
+
+// New returns a new plugin taking arguments for path and signing secret
+func New(path, signingSecret string) *Plugin {
+	return &Plugin{
+		registry:      &registry{},
+		Path:          path,
+		SigningSecret: signingSecret,
+	}
+}
+
+// Load installs the webhook
+func (p Plugin) Load(r *bot.Robot) {
+	p.logger = r.Logger
+	r.Router.HandleFunc(p.Path, p.webhook)
+}
+
+func (p Plugin) webhook(w http.ResponseWriter, r *http.Request) {
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	r.Body = ioutil.NopCloser(bytes.NewBuffer(b))
+	p.logger.Debugf("slack/command: Received webhook to %s\n", p.Path)
+
+	secrets, err := signing.Secrets(p.SigningSecret, p.SigningSecrets, p.SecretsProvider)
+	if err != nil {
+		p.logger.Errorf("slack/command: Unable to get signing secrets: %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	matched, err := signing.Verify(r.Header, b, secrets, p.Tolerance)
+	if err != nil {
+		p.logger.Errorf("slack/command: Invalid webhook: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	p.logger.Debugf("slack/command: Webhook signed with secret %d of %d\n", matched+1, len(secrets))
+
+	sc, err := slack.SlashCommandParse(r)
+	if err != nil {
+		p.logger.Errorf("slack/command: Invalid webhook: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	cmd := Command{SlashCommand: sc}
+	fn, ok := p.handler(cmd.Command)
+	if !ok {
+		p.logger.Errorf("slack/command: No handler for %s\n", cmd.Command)
+		return
+	}
+
+	done := make(chan *Response, 1)
+	go func() { done <- fn(cmd) }()
+
+	select {
+	case resp := <-done:
+		p.write(w, cmd, resp)
+	case <-time.After(responseTimeout):
+		go p.respondLater(cmd, done)
+	}
+}
+
+// write sends the handler's response in the body of the webhook's reply
+func (p Plugin) write(w http.ResponseWriter, cmd Command, resp *Response) {
+	if resp == nil {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		p.logger.Errorf("slack/command: Unable to respond to %s: %v\n", cmd.Command, err)
+	}
+}
+
+// respondLater posts the response of a slow handler to the response_url
+func (p Plugin) respondLater(cmd Command, done <-chan *Response) {
+	resp := <-done
+	if resp == nil {
+		return
+	}
+	if err := cmd.Respond(*resp); err != nil {
+		p.logger.Errorf("slack/command: Unable to respond to %s: %v\n", cmd.Command, err)
+	}
+}
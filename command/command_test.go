@@ -0,0 +1,122 @@
+package command
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/botopolis/bot/mock"
+	"github.com/botopolis/slack/internal/signing"
+	"github.com/botopolis/slack/internal/signing/signingtest"
+	"github.com/stretchr/testify/assert"
+)
+
+const signingSecret = signingtest.Secret
+
+func init() {
+	signing.Default.Now = signingtest.Now
+}
+
+// signedRequest builds a slash command request, signed with the test
+// signing secret
+func signedRequest(form url.Values) *http.Request {
+	return signingtest.Request(signingSecret, form.Encode())
+}
+
+func newTestPlugin() Plugin {
+	return Plugin{SigningSecret: signingSecret, registry: &registry{}, logger: mock.NewLogger()}
+}
+
+func TestWebhook_response(t *testing.T) {
+	p := newTestPlugin()
+	p.Add("deploy", func(cmd Command) *Response {
+		assert.Equal(t, "api production", cmd.Text)
+		assert.Equal(t, "U1234", cmd.UserID)
+		return &Response{Text: "Deploying api", InChannel: true}
+	})
+
+	recorder := httptest.NewRecorder()
+	p.webhook(recorder, signedRequest(url.Values{
+		"command": {"/deploy"},
+		"text":    {"api production"},
+		"user_id": {"U1234"},
+	}))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"response_type":"in_channel","text":"Deploying api"}`, recorder.Body.String())
+}
+
+func TestWebhook_invalid(t *testing.T) {
+	req := signedRequest(url.Values{"command": {"/deploy"}})
+	req.Header.Set("X-Slack-Signature", "v0=bad")
+
+	recorder := httptest.NewRecorder()
+	newTestPlugin().webhook(recorder, req)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestWebhook_rotatedSecrets(t *testing.T) {
+	p := newTestPlugin()
+	p.SigningSecret = "new secret"
+	p.SigningSecrets = []string{signingSecret}
+	p.Add("deploy", func(Command) *Response { return nil })
+
+	recorder := httptest.NewRecorder()
+	p.webhook(recorder, signedRequest(url.Values{"command": {"/deploy"}}))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	p.SecretsProvider = func() ([]string, error) { return []string{"new secret"}, nil }
+	recorder = httptest.NewRecorder()
+	p.webhook(recorder, signedRequest(url.Values{"command": {"/deploy"}}))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	p.SecretsProvider = func() ([]string, error) { return nil, errors.New("vault sealed") }
+	recorder = httptest.NewRecorder()
+	p.webhook(recorder, signedRequest(url.Values{"command": {"/deploy"}}))
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+}
+
+func TestWebhook_delayed(t *testing.T) {
+	responseTimeout = time.Millisecond
+	defer func() { responseTimeout = 2500 * time.Millisecond }()
+
+	posted := make(chan map[string]string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		posted <- body
+	}))
+	defer server.Close()
+
+	release := make(chan bool)
+	p := newTestPlugin()
+	p.Add("/deploy", func(cmd Command) *Response {
+		<-release
+		return &Response{Text: "Deployed"}
+	})
+
+	recorder := httptest.NewRecorder()
+	p.webhook(recorder, signedRequest(url.Values{
+		"command":      {"/deploy"},
+		"response_url": {server.URL},
+	}))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Empty(t, recorder.Body.String())
+
+	close(release)
+	assert.Equal(t, map[string]string{"response_type": "ephemeral", "text": "Deployed"}, <-posted)
+}
+
+func TestCommandRespond(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	cmd := Command{}
+	cmd.ResponseURL = server.URL
+	assert.Error(t, cmd.Respond(Response{Text: "too late"}))
+}
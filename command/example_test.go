@@ -0,0 +1,46 @@
+package command_test
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/botopolis/bot"
+	"github.com/botopolis/slack"
+	"github.com/botopolis/slack/command"
+)
+
+type ExamplePlugin struct{}
+
+func (p ExamplePlugin) Load(r *bot.Robot) {
+	var commands command.Plugin
+	if ok := r.Plugin(&commands); !ok {
+		r.Logger.Error("Example plugin requires slack/command.Plugin")
+		return
+	}
+
+	// respond straight away
+	commands.Add("/ping", func(cmd command.Command) *command.Response {
+		return &command.Response{Text: "pong"}
+	})
+
+	// acknowledge, then respond once the work is done
+	commands.Add("/deploy", func(cmd command.Command) *command.Response {
+		go func() {
+			time.Sleep(time.Minute)
+			cmd.Respond(command.Response{
+				Text:      fmt.Sprintf("<@%s> deployed %s", cmd.UserID, cmd.Text),
+				InChannel: true,
+			})
+		}()
+		return &command.Response{Text: "Deploying " + cmd.Text}
+	})
+}
+
+func Example() {
+	bot.New(
+		slack.New(os.Getenv("SLACK_TOKEN")),
+		command.New("/slack/commands", os.Getenv("SLACK_SIGNING_SECRET")),
+		ExamplePlugin{},
+	).Run()
+}
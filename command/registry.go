@@ -0,0 +1,52 @@
+package command
+
+import (
+	"strings"
+	"sync"
+)
+
+// Handler handles a slash command. The Response it returns is shown to
+// the user; return nil to show nothing, or to respond later on with
+// Command.Respond.
+type Handler func(Command) *Response
+
+type registry struct {
+	once     sync.Once
+	handlers map[string]Handler
+	mu       sync.Mutex
+}
+
+func (r *registry) init() {
+	r.once.Do(func() {
+		r.handlers = make(map[string]Handler)
+	})
+}
+
+// Add registers a handler for the given command, such as "/deploy"
+func (r *registry) Add(command string, fn Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.init()
+	r.handlers[name(command)] = fn
+}
+
+// Run runs the handler for the command
+func (r *registry) Run(cmd Command) *Response {
+	if fn, ok := r.handler(cmd.Command); ok {
+		return fn(cmd)
+	}
+	return nil
+}
+
+func (r *registry) handler(command string) (Handler, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.init()
+	fn, ok := r.handlers[name(command)]
+	return fn, ok
+}
+
+// name adds the leading slash if it was left out
+func name(command string) string {
+	return "/" + strings.TrimPrefix(command, "/")
+}
@@ -0,0 +1,24 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	counter := 0
+	example := func(Command) *Response {
+		counter++
+		return &Response{Text: "ok"}
+	}
+
+	r := registry{}
+	r.Add("deploy", example)
+	resp := r.Run(Command{SlashCommand: slack.SlashCommand{Command: "/deploy"}})
+	assert.Nil(t, r.Run(Command{SlashCommand: slack.SlashCommand{Command: "/rollback"}}))
+
+	assert.Equal(t, 1, counter)
+	assert.Equal(t, "ok", resp.Text)
+}
@@ -0,0 +1,60 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	adapter "github.com/botopolis/slack"
+	"github.com/nlopes/slack"
+)
+
+// Command is a slash command someone ran
+type Command struct {
+	slack.SlashCommand
+}
+
+// Response is a message sent in response to a command. It is only shown
+// to the user who ran the command unless InChannel is set.
+type Response struct {
+	Text   string
+	Blocks []adapter.Block
+	// InChannel shows the response to everyone in the channel
+	InChannel bool
+}
+
+// MarshalJSON builds the response payload
+func (r Response) MarshalJSON() ([]byte, error) {
+	responseType := "ephemeral"
+	if r.InChannel {
+		responseType = "in_channel"
+	}
+
+	return json.Marshal(struct {
+		ResponseType string          `json:"response_type"`
+		Text         string          `json:"text,omitempty"`
+		Blocks       []adapter.Block `json:"blocks,omitempty"`
+	}{responseType, r.Text, r.Blocks})
+}
+
+// Respond posts a response to the command's response_url. This can be
+// done after the handler has returned, for work which takes longer than
+// the 3 seconds Slack waits for.
+func (c Command) Respond(r Response) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.Post(c.ResponseURL, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("slack/command: response_url returned %s", resp.Status)
+	}
+	return nil
+}
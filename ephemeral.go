@@ -0,0 +1,86 @@
+package slack
+
+import (
+	"errors"
+
+	"github.com/botopolis/bot"
+	"github.com/botopolis/slack/internal/webapi"
+	"github.com/nlopes/slack"
+)
+
+// ephemeral wraps a message's params so transports post it with
+// chat.postEphemeral
+type ephemeral struct{ Params interface{} }
+
+// Ephemeral sends a message only m.User can see in m.Room, both of which
+// default to those of the message in the Envelope. Params may be nil,
+// Thread, slack.PostMessageParameters or Blocks. If the user isn't in the
+// room, the message is sent to them directly instead.
+func (a *Adapter) Ephemeral(m bot.Message) error {
+	if emptyMessage(m) {
+		return nil
+	}
+
+	if err := a.parse(
+		&m,
+		parseRoom,
+		parseUser,
+		parseParams,
+	); err != nil {
+		return err
+	}
+
+	if m.Room == "" {
+		return errors.New("No room provided")
+	}
+	if m.User == "" {
+		return errors.New("No user provided")
+	}
+
+	msg := m
+	msg.Params = ephemeral{m.Params}
+	_, err := a.proxy.Send(msg)
+	if err != nil && err.Error() == "user_not_in_channel" {
+		m.Room = ""
+		m.Params = unthreaded(m.Params)
+		return a.Direct(m)
+	}
+	return err
+}
+
+// unthreaded removes the thread from params, for a message which is sent
+// somewhere else than where the thread is
+func unthreaded(params interface{}) interface{} {
+	switch pm := params.(type) {
+	case Thread:
+		return nil
+	case slack.PostMessageParameters:
+		pm.ThreadTimestamp = ""
+		pm.ReplyBroadcast = false
+		return pm
+	case Blocks:
+		pm.ThreadTimestamp = ""
+		pm.ReplyBroadcast = false
+		return pm
+	}
+	return params
+}
+
+// postEphemeral sends a message with chat.postEphemeral, which the slack
+// client can't do with blocks yet.
+func (a *Adapter) postEphemeral(m bot.Message, params interface{}) (MessageRef, error) {
+	m.Params = params
+	values, err := messageValues(m)
+	if err != nil {
+		return MessageRef{}, err
+	}
+	values.Set("user", m.User)
+
+	// Ephemeral messages can't be updated or deleted, so there's no
+	// reference to return
+	var resp struct {
+		slack.SlackResponse
+		Timestamp string `json:"message_ts"`
+	}
+	return MessageRef{}, webapi.Form(a.token, "chat.postEphemeral", values, &resp)
+}
@@ -0,0 +1,129 @@
+package slack
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/botopolis/bot"
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEphemeral_parse(t *testing.T) {
+	envelope := slack.Message{}
+	envelope.User = "U1234"
+	envelope.Channel = "C1234"
+
+	store := newTestStore()
+	store.Channel.ID = "C1234"
+	store.Channel.Name = "general"
+	store.User.ID = "U4321"
+	store.User.Name = "Jean"
+
+	cases := []struct {
+		In  bot.Message
+		Out bot.Message
+		Err bool
+	}{
+		{
+			In:  bot.Message{Text: "foo", Envelope: envelope},
+			Out: bot.Message{Room: "C1234", User: "U1234", Text: "foo", Envelope: envelope, Params: ephemeral{}},
+		},
+		{
+			In:  bot.Message{Room: "general", User: "Jean", Text: "foo", Params: Thread{Timestamp: "1.1"}},
+			Out: bot.Message{Room: "C1234", User: "U4321", Text: "foo", Params: ephemeral{Thread{Timestamp: "1.1"}}},
+		},
+		{
+			In:  bot.Message{Room: "general", Text: "foo"},
+			Err: true,
+		},
+		{
+			In:  bot.Message{Room: "random", User: "Jean", Text: "foo"},
+			Err: true,
+		},
+	}
+
+	for _, c := range cases {
+		proxy, run := setUpProxySend(t, c.Out)
+		adapter := Adapter{Store: store, proxy: proxy}
+		err := adapter.Ephemeral(c.In)
+		if c.Err {
+			assert.Error(t, err)
+			assert.False(t, *run)
+		} else {
+			assert.NoError(t, err)
+			assert.True(t, *run)
+		}
+	}
+}
+
+func TestAdapterEphemeral(t *testing.T) {
+	var form map[string][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/chat.postEphemeral", r.URL.Path)
+		r.ParseForm()
+		form = r.PostForm
+		w.Write([]byte(`{"ok":true,"message_ts":"1.2"}`))
+	}))
+	defer server.Close()
+
+	defer useAPI(server.URL)()
+
+	a := New("xoxb-1")
+	a.Robot = &bot.Robot{}
+	a.ChannelRate = 0
+	assert.NoError(t, a.Ephemeral(bot.Message{Room: "C1234", User: "U1234", Text: "Not allowed"}))
+	assert.Equal(t, "C1234", form["channel"][0])
+	assert.Equal(t, "U1234", form["user"][0])
+	assert.Equal(t, "Not allowed", form["text"][0])
+	assert.Empty(t, form["blocks"])
+
+	params := testBlocks
+	params.ThreadTimestamp = "1.1"
+	assert.NoError(t, a.Ephemeral(bot.Message{Room: "C1234", User: "U1234", Params: params}))
+	blocks, _ := json.Marshal(testBlocks.Blocks)
+	assert.Equal(t, testBlocks.fallback(), form["text"][0])
+	assert.Equal(t, string(blocks), form["blocks"][0])
+	assert.Equal(t, "1.1", form["thread_ts"][0])
+
+	attachments := slack.PostMessageParameters{Attachments: []slack.Attachment{{Text: "details"}}}
+	assert.NoError(t, a.Ephemeral(bot.Message{Room: "C1234", User: "U1234", Text: "Not allowed", Params: attachments}))
+	assert.Contains(t, form["attachments"][0], `"text":"details"`)
+}
+
+func TestAdapterEphemeral_notInChannel(t *testing.T) {
+	var posted map[string][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		switch r.URL.Path {
+		case "/chat.postEphemeral":
+			w.Write([]byte(`{"ok":false,"error":"user_not_in_channel"}`))
+		case "/chat.postMessage":
+			posted = r.PostForm
+			w.Write([]byte(`{"ok":true,"channel":"D1234","ts":"1.2"}`))
+		default:
+			t.Errorf("Unexpected call to %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	defer useAPI(server.URL)()
+
+	store := newTestStore()
+	store.IM.ID = "D1234"
+	store.IM.User = "U1234"
+
+	a := New("xoxb-1")
+	a.Robot = &bot.Robot{}
+	a.Store = store
+	params := testBlocks
+	params.ThreadTimestamp = "1.1"
+	assert.NoError(t, a.Ephemeral(bot.Message{Room: "C1234", User: "U1234", Params: params}))
+
+	if assert.NotNil(t, posted) {
+		assert.Equal(t, "D1234", posted["channel"][0])
+		assert.Empty(t, posted["thread_ts"])
+	}
+}
@@ -0,0 +1,197 @@
+package slack
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/botopolis/bot"
+	"github.com/botopolis/slack/internal/signing"
+	"github.com/nlopes/slack"
+	"github.com/nlopes/slack/slackevents"
+)
+
+// seenEvents is how many event IDs we remember to skip Slack's retries of
+// events we've already received
+const seenEvents = 1000
+
+// eventsProxy receives events from Slack's Events API over HTTP. It
+// mounts a webhook on the robot's router and feeds everything it gets
+// into proxy.Forward.
+type eventsProxy struct {
+	*webProxy
+	path          string
+	signingSecret string
+
+	events chan slack.RTMEvent
+
+	// seen are the IDs of the latest events received, oldest first in
+	// seenOrder
+	seenMu    sync.Mutex
+	seen      map[string]bool
+	seenOrder []string
+}
+
+func newEventsProxy(a *Adapter, path, signingSecret string) *eventsProxy {
+	return &eventsProxy{
+		webProxy:      newWebProxy(a),
+		path:          path,
+		signingSecret: signingSecret,
+		events:        make(chan slack.RTMEvent, 32),
+	}
+}
+
+// mount installs the webhook on the robot's router
+func (p *eventsProxy) mount(r *bot.Robot) {
+	r.Router.HandleFunc(p.path, p.webhook)
+}
+
+func (p *eventsProxy) Connect() chan bot.Message {
+	in := make(chan slack.RTMEvent)
+	go p.ManageConnection(in)
+	ch := make(chan bot.Message, 32)
+	go p.Forward(in, ch)
+	return ch
+}
+
+// ManageConnection finds out who we are, retrying with an exponential
+// backoff until Slack answers, then passes on events from the webhook
+// until Disconnect is called.
+func (p *eventsProxy) ManageConnection(out chan<- slack.RTMEvent) {
+	defer close(out)
+	disconnected := slack.RTMEvent{Type: "disconnected", Data: &slack.DisconnectedEvent{Intentional: true}}
+
+	for count := 1; ; count++ {
+		out <- slack.RTMEvent{Type: "connecting", Data: &slack.ConnectingEvent{Attempt: count}}
+		ev, err := p.connected(1)
+		if err == nil {
+			out <- ev
+			break
+		}
+
+		if err.Error() == errInvalidAuth.Error() {
+			out <- slack.RTMEvent{Type: "invalid_auth", Data: &slack.InvalidAuthEvent{}}
+		} else {
+			out <- slack.RTMEvent{
+				Type: "connection_error",
+				Data: &slack.ConnectionErrorEvent{Attempt: count, ErrorObj: err},
+			}
+		}
+
+		select {
+		case <-p.stop:
+			out <- disconnected
+			return
+		case <-time.After(p.backoff.Duration()):
+		}
+	}
+
+	for {
+		select {
+		case ev := <-p.events:
+			out <- ev
+		case <-p.stop:
+			out <- disconnected
+			return
+		}
+	}
+}
+
+func (p *eventsProxy) Disconnect() { p.halt() }
+
+func (p *eventsProxy) webhook(w http.ResponseWriter, r *http.Request) {
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	secrets, err := signing.Secrets(p.signingSecret, p.SigningSecrets, p.SecretsProvider)
+	if err != nil {
+		p.Robot.Logger.Errorf("slack: Unable to get signing secrets: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	matched, err := signing.Verify(r.Header, b, secrets, p.Tolerance)
+	if err != nil {
+		p.Robot.Logger.Errorf("slack: Invalid Events API request: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	p.Robot.Logger.Debugf("slack: Events API request signed with secret %d of %d", matched+1, len(secrets))
+
+	var body struct {
+		Type      string          `json:"type"`
+		Challenge string          `json:"challenge"`
+		EventID   string          `json:"event_id"`
+		Event     json.RawMessage `json:"event"`
+	}
+	if err := json.Unmarshal(b, &body); err != nil {
+		p.Robot.Logger.Errorf("slack: Invalid Events API request: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	switch body.Type {
+	case slackevents.URLVerification:
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(body.Challenge))
+	case slackevents.CallbackEvent:
+		if n := r.Header.Get("X-Slack-Retry-Num"); n != "" {
+			p.Robot.Logger.Debugf("slack: Events API retry %s of %s: %s", n, body.EventID, r.Header.Get("X-Slack-Retry-Reason"))
+		}
+		if !p.firstDelivery(body.EventID) {
+			return
+		}
+
+		ev, err := rtmEvent(body.Event)
+		if err != nil {
+			p.Robot.Logger.Debugf("slack: Skipping Events API event: %v", err)
+			return
+		}
+
+		// Slack wants an answer within 3 seconds, so don't wait for the
+		// robot to catch up. Slack will retry the event later.
+		select {
+		case p.events <- ev:
+		default:
+			p.forget(body.EventID)
+			p.Robot.Logger.Errorf("slack: Too many Events API events queued, dropping %s", body.EventID)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}
+}
+
+// firstDelivery records an event ID, reporting whether it's the first
+// time we've received it
+func (p *eventsProxy) firstDelivery(id string) bool {
+	if id == "" {
+		return true
+	}
+
+	p.seenMu.Lock()
+	defer p.seenMu.Unlock()
+	if p.seen[id] {
+		return false
+	}
+	if p.seen == nil {
+		p.seen = make(map[string]bool)
+	}
+	if len(p.seenOrder) == seenEvents {
+		delete(p.seen, p.seenOrder[0])
+		p.seenOrder = p.seenOrder[1:]
+	}
+	p.seen[id] = true
+	p.seenOrder = append(p.seenOrder, id)
+	return true
+}
+
+// forget lets an event we couldn't take be delivered again
+func (p *eventsProxy) forget(id string) {
+	p.seenMu.Lock()
+	defer p.seenMu.Unlock()
+	delete(p.seen, id)
+}
@@ -0,0 +1,195 @@
+package slack
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/botopolis/bot"
+	"github.com/botopolis/bot/mock"
+	"github.com/botopolis/slack/internal/signing"
+	"github.com/botopolis/slack/internal/signing/signingtest"
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+const eventsSecret = signingtest.Secret
+
+func init() {
+	signing.Default.Now = signingtest.Now
+}
+
+func TestEventsProxy_webhook(t *testing.T) {
+	cases := []struct {
+		Name string
+		Req  *http.Request
+		Code int
+		Body string
+	}{
+		{
+			Name: "With a bad signature",
+			Req:  signingtest.Request("nope", `{"type":"url_verification","challenge":"abc"}`),
+			Code: http.StatusBadRequest,
+		},
+		{
+			Name: "With no signature",
+			Req:  httptest.NewRequest("POST", "/events", bytes.NewBufferString(`{}`)),
+			Code: http.StatusBadRequest,
+		},
+		{
+			Name: "With a non-JSON body",
+			Req:  signingtest.Request(eventsSecret, `<xml></xml>`),
+			Code: http.StatusBadRequest,
+		},
+		{
+			Name: "With a url_verification challenge",
+			Req:  signingtest.Request(eventsSecret, `{"type":"url_verification","challenge":"abc"}`),
+			Code: http.StatusOK,
+			Body: "abc",
+		},
+	}
+
+	a := NewEventsAPI("/events", eventsSecret, "")
+	a.Robot = &bot.Robot{Logger: mock.NewLogger()}
+	p := a.proxy.(*queue).transport.(*eventsProxy)
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			p.webhook(recorder, c.Req)
+			assert.Equal(t, c.Code, recorder.Code)
+			assert.Equal(t, c.Body, recorder.Body.String())
+		})
+	}
+}
+
+func TestEventsProxy_replay(t *testing.T) {
+	a := NewEventsAPI("/events", eventsSecret, "")
+	a.Robot = &bot.Robot{Logger: mock.NewLogger()}
+	p := a.proxy.(*queue).transport.(*eventsProxy)
+
+	body := `{"type":"url_verification","challenge":"abc"}`
+	req := signingtest.Request(eventsSecret, body)
+	replay := httptest.NewRequest("POST", "/events", bytes.NewBufferString(body))
+	replay.Header = req.Header.Clone()
+
+	recorder := httptest.NewRecorder()
+	p.webhook(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	p.webhook(recorder, replay)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestEventsProxy_rotatedSecrets(t *testing.T) {
+	a := NewEventsAPI("/events", "new secret", "")
+	a.Robot = &bot.Robot{Logger: mock.NewLogger()}
+	a.SigningSecrets = []string{eventsSecret}
+	p := a.proxy.(*queue).transport.(*eventsProxy)
+
+	body := `{"type":"url_verification","challenge":"abc"}`
+	recorder := httptest.NewRecorder()
+	p.webhook(recorder, signingtest.Request(eventsSecret, body))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	a.SecretsProvider = func() ([]string, error) { return []string{"new secret"}, nil }
+	recorder = httptest.NewRecorder()
+	p.webhook(recorder, signingtest.Request(eventsSecret, body))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestEventsProxy_messages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/auth.test":
+			w.Write([]byte(`{"ok":true,"user_id":"B1234","user":"beardroid"}`))
+		default:
+			w.Write([]byte(`{"ok":false,"error":"unknown_method"}`))
+		}
+	}))
+	defer server.Close()
+
+	defer useAPI(server.URL)()
+
+	store := newTestStore()
+	store.User = slack.User{ID: "U1234", Name: "jean"}
+	store.Channel.ID = "C1234"
+	store.Channel.Name = "general"
+
+	a := NewEventsAPI("/events", eventsSecret, "xoxb-1")
+	a.Store = store
+	p := a.proxy.(*queue).transport.(*eventsProxy)
+	a.Robot = &bot.Robot{Logger: mock.NewLogger()}
+
+	ch := a.Messages()
+	recorder := httptest.NewRecorder()
+	p.webhook(recorder, signingtest.Request(eventsSecret, `{
+		"type": "event_callback",
+		"event": {"type": "message", "channel": "C1234", "user": "U1234", "text": "hi"}
+	}`))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	m := <-ch
+	assert.Equal(t, "B1234", a.BotID)
+	assert.Equal(t, "jean", m.User)
+	assert.Equal(t, "general", m.Room)
+	assert.Equal(t, "hi", m.Text)
+
+	a.Unload(a.Robot)
+	_, ok := <-ch
+	assert.False(t, ok)
+}
+
+func TestEventsProxy_retries(t *testing.T) {
+	a := NewEventsAPI("/events", eventsSecret, "")
+	a.Robot = &bot.Robot{Logger: mock.NewLogger()}
+	p := a.proxy.(*queue).transport.(*eventsProxy)
+	p.events = make(chan slack.RTMEvent, 1)
+
+	event := func(id string) *http.Request {
+		return signingtest.Request(eventsSecret, `{
+			"type": "event_callback",
+			"event_id": "`+id+`",
+			"event": {"type": "message", "channel": "C1234", "user": "U1234", "text": "hi"}
+		}`)
+	}
+
+	recorder := httptest.NewRecorder()
+	p.webhook(recorder, event("Ev1"))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	// A retry is acknowledged but not delivered again
+	retry := event("Ev1")
+	retry.Header.Set("X-Slack-Retry-Num", "1")
+	recorder = httptest.NewRecorder()
+	p.webhook(recorder, retry)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	// With nowhere to put it, an event is turned away without blocking
+	recorder = httptest.NewRecorder()
+	p.webhook(recorder, event("Ev2"))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+
+	<-p.events
+	assert.Len(t, p.events, 0)
+
+	// and taken when Slack retries it
+	recorder = httptest.NewRecorder()
+	p.webhook(recorder, event("Ev2"))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Len(t, p.events, 1)
+}
+
+func TestEventsProxy_firstDelivery(t *testing.T) {
+	p := eventsProxy{}
+	for i := 0; i < seenEvents; i++ {
+		assert.True(t, p.firstDelivery(strconv.Itoa(i)))
+	}
+	assert.False(t, p.firstDelivery("0"))
+	assert.True(t, p.firstDelivery("new"))
+	assert.True(t, p.firstDelivery("0"), "oldest forgotten")
+	assert.True(t, p.firstDelivery(""))
+	assert.True(t, p.firstDelivery(""))
+}
@@ -1,8 +1,10 @@
 package slack_test
 
 import (
+	"bytes"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/botopolis/bot"
 	"github.com/botopolis/slack"
@@ -23,11 +25,127 @@ func Example() {
 	robot.Run()
 }
 
+func ExampleNewSocketMode() {
+	robot := bot.New(
+		slack.NewSocketMode(os.Getenv("SLACK_APP_TOKEN"), os.Getenv("SLACK_BOT_TOKEN")),
+	)
+	robot.Run()
+}
+
+func ExampleNewEventsAPI() {
+	robot := bot.New(
+		slack.NewEventsAPI("/slack/events", os.Getenv("SLACK_SIGNING_SECRET"), os.Getenv("SLACK_BOT_TOKEN")),
+	)
+	robot.Run()
+}
+
+func ExampleNewFileStore() {
+	adapter := slack.New(os.Getenv("SLACK_TOKEN"))
+	store, err := slack.NewFileStore("/var/lib/bot/slack.json", adapter.Client)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	adapter.Store = store
+	bot.New(adapter).Run()
+}
+
+func ExampleAdapter_OnStateChange() {
+	adapter := slack.New(os.Getenv("SLACK_TOKEN"))
+	adapter.OnStateChange(func(c slack.StateChange) {
+		if c.To == slack.Disconnected && c.Err != nil {
+			fmt.Println("lost slack:", c.Err)
+		}
+	})
+	bot.New(adapter).Run()
+}
+
 func ExampleAdapter_Send() {
 	adapter := slack.New(os.Getenv("SLACK_TOKEN"))
 	adapter.Send(bot.Message{Text: "hello!"})
 }
 
+func ExampleAdapter_ReplyInThread() {
+	robot := bot.New(slack.New(os.Getenv("SLACK_TOKEN")))
+	robot.Respond(bot.Regexp("status"), func(r bot.Responder) error {
+		adapter := r.Chat.(*slack.Adapter)
+		return adapter.ReplyInThread(bot.Message{
+			Text:     "All systems go",
+			Envelope: r.Envelope,
+			Params:   slack.Thread{Broadcast: true},
+		})
+	})
+	robot.Run()
+}
+
+func ExampleAdapter_Ephemeral() {
+	robot := bot.New(slack.New(os.Getenv("SLACK_TOKEN")))
+	robot.Respond(bot.Regexp("deploy"), func(r bot.Responder) error {
+		adapter := r.Chat.(*slack.Adapter)
+		return adapter.Ephemeral(bot.Message{
+			Text:     "You don't have permission to deploy",
+			Envelope: r.Envelope,
+		})
+	})
+	robot.Run()
+}
+
+func ExampleAdapter_Update() {
+	adapter := slack.New(os.Getenv("SLACK_TOKEN"))
+	ref, err := adapter.Post(bot.Message{Room: "deploys", Text: "Deploy running…"})
+	if err != nil {
+		return
+	}
+	// deploy, then
+	adapter.Update(ref, bot.Message{Text: "Deploy finished"})
+}
+
+func ExampleAdapter_Schedule() {
+	adapter := slack.New(os.Getenv("SLACK_TOKEN"))
+	at := time.Now().Add(24 * time.Hour)
+	id, err := adapter.Schedule(bot.Message{Room: "standup", Text: "Standup time!"}, at)
+	if err != nil {
+		return
+	}
+	// standup was called off
+	adapter.Unschedule("standup", id)
+}
+
+func ExampleAdapter_Upload() {
+	adapter := slack.New(os.Getenv("SLACK_TOKEN"))
+	f, err := os.Open("build.log")
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	adapter.Upload(f, slack.Upload{
+		Filename: "build.log",
+		Title:    "Build #42",
+		Comment:  "The build failed",
+		Rooms:    []string{"builds"},
+	})
+}
+
+func ExampleFiles() {
+	robot := bot.New(slack.New(os.Getenv("SLACK_TOKEN")))
+	robot.Hear(bot.Regexp(""), func(r bot.Responder) error {
+		adapter := r.Chat.(*slack.Adapter)
+		for _, f := range slack.Files(r.Message) {
+			if f.Filetype != "csv" {
+				continue
+			}
+			var buf bytes.Buffer
+			if _, err := adapter.Download(f, &buf, 0); err != nil {
+				return err
+			}
+			// import buf's rows
+		}
+		return nil
+	})
+	robot.Run()
+}
+
 func ExampleAdapter_Send_custom() {
 	adapter := slack.New(os.Getenv("SLACK_TOKEN"))
 	adapter.Send(bot.Message{Params: slacker.PostMessageParameters{
@@ -46,6 +164,25 @@ func ExampleAdapter_Send_custom() {
 	}})
 }
 
+func ExampleAdapter_Send_blocks() {
+	adapter := slack.New(os.Getenv("SLACK_TOKEN"))
+	adapter.Send(bot.Message{
+		Room: "general",
+		Params: slack.Blocks{Blocks: []slack.Block{
+			slack.Header{Text: "Nightly build"},
+			slack.Section{
+				Text:   slack.Markdown("*Passed* in 4m12s"),
+				Fields: []*slack.Text{slack.Markdown("*Tests*\n1024"), slack.Markdown("*Coverage*\n87%")},
+			},
+			slack.Context{Elements: []slack.Element{slack.PlainText("Triggered by beardroid")}},
+			slack.Actions{Elements: []slack.Element{
+				slack.Button{ActionID: "rerun", Text: "Run again", Style: "primary"},
+				slack.Button{Text: "Logs", URL: "https://ci.example.com/builds/42"},
+			}},
+		}},
+	})
+}
+
 func ExampleAdapter_Reply() {
 	adapter := slack.New(os.Getenv("SLACK_TOKEN"))
 	fromMessage := bot.Message{
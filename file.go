@@ -0,0 +1,137 @@
+package slack
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/botopolis/bot"
+	"github.com/nlopes/slack"
+)
+
+// DefaultDownloadLimit is the most Adapter.Download reads when no limit is
+// given
+const DefaultDownloadLimit = 10 << 20
+
+// ErrFileTooLarge is returned when downloading a file over the limit
+var ErrFileTooLarge = errors.New("slack: File is larger than the download limit")
+
+// slackHost reports whether a file is hosted by Slack, as only those get
+// the bot token. It's swapped out in tests.
+var slackHost = func(u *url.URL) bool {
+	host := u.Hostname()
+	return u.Scheme == "https" && (host == "slack.com" || strings.HasSuffix(host, ".slack.com"))
+}
+
+// File is a file or snippet shared in an incoming message (see Files)
+type File struct {
+	ID       string
+	Name     string
+	Title    string
+	Mimetype string
+	// Filetype is Slack's name for the type, such as csv, png or go
+	Filetype string
+	// Snippet is set for text snippets, rather than uploaded files
+	Snippet bool
+	Size    int64
+	// URLPrivate needs the bot token to be fetched (see Adapter.Download)
+	URLPrivate string
+	Permalink  string
+}
+
+// Files returns the files shared in an incoming message, if any
+func Files(m bot.Message) []File {
+	msg, ok := m.Envelope.(slack.Message)
+	if !ok {
+		return nil
+	}
+
+	var files []File
+	for _, f := range msg.Files {
+		files = append(files, File{
+			ID:         f.ID,
+			Name:       f.Name,
+			Title:      f.Title,
+			Mimetype:   f.Mimetype,
+			Filetype:   f.Filetype,
+			Snippet:    f.Mode == "snippet",
+			Size:       int64(f.Size),
+			URLPrivate: f.URLPrivate,
+			Permalink:  f.Permalink,
+		})
+	}
+	return files
+}
+
+// legacyFile adds the single file older message events carry to the
+// event's Files, unless it's already there. The slack client drops it, so
+// it's read from the raw event.
+func legacyFile(raw json.RawMessage, ev *slack.MessageEvent) {
+	var legacy struct {
+		File *slack.File `json:"file"`
+	}
+	if err := json.Unmarshal(raw, &legacy); err != nil || legacy.File == nil {
+		return
+	}
+
+	for _, f := range ev.Files {
+		if f.ID == legacy.File.ID {
+			return
+		}
+	}
+	ev.Files = append([]slack.File{*legacy.File}, ev.Files...)
+}
+
+// Download writes the content of a shared file to w, authenticating with
+// the bot token if Slack hosts it. It reads at most limit bytes (DefaultDownloadLimit if
+// it's zero), returning ErrFileTooLarge for larger files.
+func (a *Adapter) Download(f File, w io.Writer, limit int64) (int64, error) {
+	if limit <= 0 {
+		limit = DefaultDownloadLimit
+	}
+	if f.URLPrivate == "" {
+		return 0, errors.New("No file to download")
+	}
+	if f.Size > limit {
+		return 0, ErrFileTooLarge
+	}
+
+	req, err := http.NewRequest("GET", f.URLPrivate, nil)
+	if err != nil {
+		return 0, err
+	}
+	if slackHost(req.URL) {
+		req.Header.Set("Authorization", "Bearer "+a.token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("slack: Downloading %s returned %s", f.Name, resp.Status)
+	}
+	// Slack answers with its login page when the token can't see the file
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") && !strings.HasPrefix(f.Mimetype, "text/html") {
+		return 0, fmt.Errorf("slack: Not allowed to download %s, is the files:read scope missing?", f.Name)
+	}
+	if resp.ContentLength > limit {
+		return 0, ErrFileTooLarge
+	}
+
+	n, err := io.Copy(w, io.LimitReader(resp.Body, limit))
+	if err != nil {
+		return n, err
+	}
+	// Anything left over means the file was larger than Slack said
+	if more, _ := io.ReadFull(resp.Body, make([]byte, 1)); more > 0 {
+		return n, ErrFileTooLarge
+	}
+	return n, nil
+}
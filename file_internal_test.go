@@ -0,0 +1,125 @@
+package slack
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/botopolis/bot"
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFiles(t *testing.T) {
+	envelope := slack.Message{}
+	envelope.SubType = "file_share"
+	envelope.Files = []slack.File{
+		{ID: "F1", Name: "report.csv", Title: "Report", Mimetype: "text/csv", Filetype: "csv", Mode: "hosted", Size: 42, URLPrivate: "https://files.slack.com/F1"},
+		{ID: "F2", Name: "fix.diff", Filetype: "diff", Mode: "snippet", Size: 10},
+	}
+
+	files := Files(bot.Message{Envelope: envelope})
+	assert.Equal(t, []File{
+		{ID: "F1", Name: "report.csv", Title: "Report", Mimetype: "text/csv", Filetype: "csv", Size: 42, URLPrivate: "https://files.slack.com/F1"},
+		{ID: "F2", Name: "fix.diff", Filetype: "diff", Snippet: true, Size: 10},
+	}, files)
+
+	assert.Empty(t, Files(bot.Message{Envelope: slack.Message{}}))
+	assert.Empty(t, Files(bot.Message{}))
+}
+
+func TestFiles_legacy(t *testing.T) {
+	ev, err := rtmEvent([]byte(`{
+		"type": "message",
+		"subtype": "file_share",
+		"channel": "C1234",
+		"file": {"id": "F1", "name": "report.csv"}
+	}`))
+	assert.NoError(t, err)
+	envelope := slack.Message(*ev.Data.(*slack.MessageEvent))
+	assert.Equal(t, []File{{ID: "F1", Name: "report.csv"}}, Files(bot.Message{Envelope: envelope}))
+
+	ev, err = rtmEvent([]byte(`{
+		"type": "message",
+		"channel": "C1234",
+		"file": {"id": "F1", "name": "report.csv"},
+		"files": [{"id": "F1", "name": "report.csv"}, {"id": "F2", "name": "fix.diff"}]
+	}`))
+	assert.NoError(t, err)
+	envelope = slack.Message(*ev.Data.(*slack.MessageEvent))
+	assert.Equal(t, []File{{ID: "F1", Name: "report.csv"}, {ID: "F2", Name: "fix.diff"}}, Files(bot.Message{Envelope: envelope}))
+}
+
+func TestSlackHost(t *testing.T) {
+	cases := map[string]bool{
+		"https://files.slack.com/files-pri/T1-F1/report.csv": true,
+		"https://slack.com/F1":                               true,
+		"http://files.slack.com/F1":                          false,
+		"https://files.slack.com.example.com/F1":             false,
+		"https://example.com/files.slack.com/F1":             false,
+		"https://notslack.com/F1":                            false,
+	}
+	for raw, want := range cases {
+		u, err := url.Parse(raw)
+		assert.NoError(t, err)
+		assert.Equal(t, want, slackHost(u), raw)
+	}
+}
+
+func TestAdapterDownload(t *testing.T) {
+	content := "name,count\nfoo,1\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer xoxb-1" {
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html>Sign in</html>"))
+			return
+		}
+		w.Header().Set("Content-Type", "text/csv")
+		w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	defer func(host func(*url.URL) bool) { slackHost = host }(slackHost)
+	slackHost = func(u *url.URL) bool { return u.Host == strings.TrimPrefix(server.URL, "http://") }
+
+	f := File{Name: "report.csv", Mimetype: "text/csv", Size: int64(len(content)), URLPrivate: server.URL + "/F1"}
+
+	var buf bytes.Buffer
+	n, err := New("xoxb-1").Download(f, &buf, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)), n)
+	assert.Equal(t, content, buf.String())
+
+	// Too large by Slack's account, or once downloaded
+	buf.Reset()
+	_, err = New("xoxb-1").Download(f, &buf, 5)
+	assert.Equal(t, ErrFileTooLarge, err)
+	assert.Empty(t, buf.String())
+
+	f.Size = 0
+	_, err = New("xoxb-1").Download(f, &buf, 5)
+	assert.Equal(t, ErrFileTooLarge, err)
+	assert.True(t, buf.Len() <= 5)
+
+	_, err = New("xoxb-2").Download(f, &buf, 0)
+	assert.Error(t, err)
+
+	_, err = New("xoxb-1").Download(File{}, &buf, 0)
+	assert.Error(t, err)
+}
+
+func TestAdapterDownload_elsewhere(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("Authorization"))
+		w.Write([]byte("name,count\n"))
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	_, err := New("xoxb-1").Download(File{Name: "report.csv", URLPrivate: server.URL + "/F1"}, &buf, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "name,count\n", buf.String())
+}
@@ -0,0 +1,140 @@
+// Package signing verifies that HTTP requests were sent by Slack
+package signing
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nlopes/slack"
+)
+
+// DefaultTolerance is how far a request's timestamp may be from our clock
+// when no tolerance is given
+const DefaultTolerance = 5 * time.Minute
+
+// cacheSize bounds how many signatures Default remembers
+const cacheSize = 10000
+
+var (
+	// ErrStale is returned for requests signed too long ago (or too far
+	// in the future)
+	ErrStale = errors.New("Request timestamp is outside of the tolerated window")
+	// ErrSignature is returned for requests not signed with any of the
+	// secrets
+	ErrSignature = errors.New("Request signature doesn't match any signing secret")
+	// ErrReplayed is returned for requests which have already been seen
+	ErrReplayed = errors.New("Request has already been received")
+)
+
+// Default is shared by every webhook in this project, so a request can't
+// be replayed to another one either
+var Default = NewVerifier(cacheSize)
+
+// Verify checks a request with Default
+func Verify(h http.Header, body []byte, secrets []string, tolerance time.Duration) (int, error) {
+	return Default.Verify(h, body, secrets, tolerance)
+}
+
+// Secrets returns the secrets a request may be signed with: whatever
+// provider returns if it's set, or else secret followed by others
+func Secrets(secret string, others []string, provider func() ([]string, error)) ([]string, error) {
+	if provider != nil {
+		return provider()
+	}
+	return append([]string{secret}, others...), nil
+}
+
+// Verifier checks requests were signed by Slack recently, and remembers
+// their signatures to reject replays of the same request.
+type Verifier struct {
+	// Now is the clock requests' timestamps are checked against
+	Now func() time.Time
+
+	size int
+	mu   sync.Mutex
+	seen map[string]time.Time
+	fifo []string
+}
+
+// NewVerifier returns a Verifier remembering up to size signatures
+func NewVerifier(size int) *Verifier {
+	return &Verifier{
+		Now:  time.Now,
+		size: size,
+		seen: make(map[string]time.Time),
+	}
+}
+
+// Verify checks a request was signed by Slack with one of the given
+// secrets, at most tolerance ago (DefaultTolerance if it's zero), and
+// hasn't been seen before. It returns the index of the secret which
+// matched, so several can be accepted while rotating them.
+func (v *Verifier) Verify(h http.Header, body []byte, secrets []string, tolerance time.Duration) (int, error) {
+	if h["X-Slack-Signature"] == nil || h["X-Slack-Request-Timestamp"] == nil {
+		return -1, errors.New("Missing signing headers")
+	}
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+
+	sec, err := strconv.ParseInt(h.Get("X-Slack-Request-Timestamp"), 10, 64)
+	if err != nil {
+		return -1, err
+	}
+	ts := time.Unix(sec, 0)
+	if d := v.Now().Sub(ts); d > tolerance || d < -tolerance {
+		return -1, ErrStale
+	}
+
+	matched := -1
+	for i, secret := range secrets {
+		if secret != "" && ensure(h, body, secret) == nil {
+			matched = i
+			break
+		}
+	}
+	if matched < 0 {
+		return -1, ErrSignature
+	}
+
+	// Past the tolerance, a replay would be rejected as stale anyway
+	return matched, v.remember(h.Get("X-Slack-Signature"), ts.Add(tolerance))
+}
+
+// ensure checks the request's signature against a single secret
+func ensure(h http.Header, body []byte, secret string) error {
+	verifier, err := slack.NewSecretsVerifier(h, secret)
+	if err != nil {
+		return err
+	}
+
+	if _, err := verifier.Write(body); err != nil {
+		return err
+	}
+
+	return verifier.Ensure()
+}
+
+// remember records a signature until it expires, failing if it was
+// already there. The oldest signatures are forgotten to stay within size.
+func (v *Verifier) remember(signature string, expires time.Time) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	now := v.Now()
+	if exp, ok := v.seen[signature]; ok && now.Before(exp) {
+		return ErrReplayed
+	}
+
+	for len(v.fifo) > 0 && (len(v.fifo) >= v.size || now.After(v.seen[v.fifo[0]])) {
+		delete(v.seen, v.fifo[0])
+		v.fifo = v.fifo[1:]
+	}
+
+	v.seen[signature] = expires
+	v.fifo = append(v.fifo, signature)
+	return nil
+}
@@ -0,0 +1,129 @@
+package signing
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const secret = "e6b19c573432dcc6b075501d51b51bb8"
+
+func signed(ts time.Time, body string) http.Header {
+	sec := strconv.FormatInt(ts.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + sec + ":" + body))
+
+	h := http.Header{}
+	h.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	h.Set("X-Slack-Request-Timestamp", sec)
+	return h
+}
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.t }
+
+func newTestVerifier(size int) (*Verifier, *fakeClock) {
+	clock := &fakeClock{time.Unix(1531431954, 0)}
+	v := NewVerifier(size)
+	v.Now = clock.Now
+	return v, clock
+}
+
+// check verifies a request signed with a single secret
+func check(v *Verifier, h http.Header, body []byte, secret string, tolerance time.Duration) error {
+	_, err := v.Verify(h, body, []string{secret}, tolerance)
+	return err
+}
+
+func TestVerify(t *testing.T) {
+	v, clock := newTestVerifier(10)
+	now := clock.t
+
+	assert.Error(t, check(v, http.Header{}, []byte("a"), secret, 0))
+	assert.Error(t, check(v, signed(now, "a"), []byte("b"), secret, 0))
+	assert.Error(t, check(v, signed(now, "a"), []byte("a"), "other secret", 0))
+	assert.NoError(t, check(v, signed(now, "a"), []byte("a"), secret, 0))
+}
+
+func TestVerify_tolerance(t *testing.T) {
+	v, clock := newTestVerifier(10)
+	now := clock.t
+
+	assert.Equal(t, ErrStale, check(v, signed(now.Add(-6*time.Minute), "a"), []byte("a"), secret, 0))
+	assert.Equal(t, ErrStale, check(v, signed(now.Add(6*time.Minute), "a"), []byte("a"), secret, 0))
+	assert.NoError(t, check(v, signed(now.Add(-4*time.Minute), "a"), []byte("a"), secret, 0))
+
+	assert.Equal(t, ErrStale, check(v, signed(now.Add(-2*time.Minute), "a"), []byte("a"), secret, time.Minute))
+	assert.NoError(t, check(v, signed(now.Add(-2*time.Minute), "a"), []byte("a"), secret, 3*time.Minute))
+}
+
+func TestVerify_replay(t *testing.T) {
+	v, clock := newTestVerifier(10)
+	h := signed(clock.t, "a")
+
+	assert.NoError(t, check(v, h, []byte("a"), secret, 0))
+	assert.Equal(t, ErrReplayed, check(v, h, []byte("a"), secret, 0))
+
+	// Once the tolerance has passed, it's stale instead
+	clock.t = clock.t.Add(DefaultTolerance + time.Second)
+	assert.Equal(t, ErrStale, check(v, h, []byte("a"), secret, 0))
+}
+
+func TestVerify_cacheSize(t *testing.T) {
+	v, clock := newTestVerifier(2)
+	a, b, c := signed(clock.t, "a"), signed(clock.t, "b"), signed(clock.t, "c")
+
+	assert.NoError(t, check(v, a, []byte("a"), secret, 0))
+	assert.NoError(t, check(v, b, []byte("b"), secret, 0))
+	assert.NoError(t, check(v, c, []byte("c"), secret, 0))
+	assert.Len(t, v.seen, 2)
+
+	// The oldest signature was forgotten to make room
+	assert.NoError(t, check(v, a, []byte("a"), secret, 0))
+	assert.Equal(t, ErrReplayed, check(v, c, []byte("c"), secret, 0))
+}
+
+func TestVerify_expiry(t *testing.T) {
+	v, clock := newTestVerifier(10)
+	assert.NoError(t, check(v, signed(clock.t, "a"), []byte("a"), secret, time.Minute))
+
+	clock.t = clock.t.Add(2 * time.Minute)
+	assert.NoError(t, check(v, signed(clock.t, "b"), []byte("b"), secret, time.Minute))
+	assert.Len(t, v.seen, 1)
+}
+
+func TestVerify_rotation(t *testing.T) {
+	v, clock := newTestVerifier(10)
+
+	i, err := v.Verify(signed(clock.t, "a"), []byte("a"), []string{"new secret", secret}, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, i)
+
+	i, err = v.Verify(signed(clock.t, "b"), []byte("b"), []string{"", secret, "new secret"}, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, i)
+
+	i, err = v.Verify(signed(clock.t, "c"), []byte("c"), []string{"new secret", "other secret"}, 0)
+	assert.Equal(t, ErrSignature, err)
+	assert.Equal(t, -1, i)
+
+	_, err = v.Verify(signed(clock.t, "d"), []byte("d"), nil, 0)
+	assert.Equal(t, ErrSignature, err)
+}
+
+func TestSecrets(t *testing.T) {
+	secrets, err := Secrets("a", []string{"b", "c"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, secrets)
+
+	secrets, err = Secrets("a", []string{"b"}, func() ([]string, error) { return []string{"d"}, nil })
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"d"}, secrets)
+}
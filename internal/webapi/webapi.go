@@ -0,0 +1,70 @@
+// Package webapi calls Slack web API methods which the slack client
+// doesn't support
+package webapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nlopes/slack"
+)
+
+// Response is anything embedding slack.SlackResponse
+type Response interface{ Err() error }
+
+// Form posts values to a web API method, authenticating with token, and
+// decodes the response into v
+func Form(token, method string, values url.Values, v Response) error {
+	return post(token, method, "application/x-www-form-urlencoded", strings.NewReader(values.Encode()), v)
+}
+
+// JSON posts body as JSON to a web API method, authenticating with token,
+// and decodes the response into v
+func JSON(token, method string, body interface{}, v Response) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	return post(token, method, "application/json; charset=utf-8", bytes.NewReader(b), v)
+}
+
+// post sends a request to a web API method. Rate limits are reported as
+// *slack.RateLimitedError so they can be retried like any other call, with
+// a zero RetryAfter when Slack didn't say how long to wait.
+func post(token, method, contentType string, body io.Reader, v Response) error {
+	req, err := http.NewRequest("POST", slack.SLACK_API+method, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		after, err := strconv.ParseInt(resp.Header.Get("Retry-After"), 10, 64)
+		if err != nil {
+			return &slack.RateLimitedError{}
+		}
+		return &slack.RateLimitedError{RetryAfter: time.Duration(after) * time.Second}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("slack: %s returned %s", method, resp.Status)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return err
+	}
+	return v.Err()
+}
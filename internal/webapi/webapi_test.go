@@ -0,0 +1,46 @@
+package webapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForm_rateLimited(t *testing.T) {
+	cases := []struct {
+		Name       string
+		RetryAfter string
+		Out        time.Duration
+	}{
+		{Name: "With a Retry-After", RetryAfter: "3", Out: 3 * time.Second},
+		{Name: "Without a Retry-After", RetryAfter: "", Out: 0},
+		{Name: "With a bad Retry-After", RetryAfter: "soon", Out: 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if c.RetryAfter != "" {
+					w.Header().Set("Retry-After", c.RetryAfter)
+				}
+				w.WriteHeader(http.StatusTooManyRequests)
+			}))
+			defer server.Close()
+
+			api := slack.SLACK_API
+			slack.SLACK_API = server.URL + "/"
+			defer func() { slack.SLACK_API = api }()
+
+			var resp slack.SlackResponse
+			err := Form("xoxb-1", "chat.postMessage", url.Values{}, &resp)
+			if assert.IsType(t, &slack.RateLimitedError{}, err) {
+				assert.Equal(t, c.Out, err.(*slack.RateLimitedError).RetryAfter)
+			}
+		})
+	}
+}
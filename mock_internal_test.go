@@ -20,9 +20,11 @@ func newTestProxy() *testProxy {
 	}
 }
 
-func (p *testProxy) Connect() chan bot.Message         { return p.C }
-func (p *testProxy) Disconnect()                       {}
-func (p *testProxy) Send(m bot.Message) error          { return p.SendFunc(m) }
+func (p *testProxy) Connect() chan bot.Message { return p.C }
+func (p *testProxy) Disconnect()               {}
+func (p *testProxy) Send(m bot.Message) (MessageRef, error) {
+	return MessageRef{Channel: m.Room, Timestamp: "1.1"}, p.SendFunc(m)
+}
 func (p *testProxy) React(m bot.Message) error         { return p.ReactFunc(m) }
 func (p *testProxy) SetTopic(room, topic string) error { return p.SetTopicFunc(room, topic) }
 
@@ -44,8 +46,16 @@ func newTestStore() *testStore {
 	}
 }
 
-func (s *testStore) Load(i *slack.Info) { s.LoadFunc(i) }
-func (s *testStore) Update() error      { return s.UpdateFunc() }
+func (s *testStore) Load(i *slack.Info)          { s.LoadFunc(i) }
+func (s *testStore) Update() error               { return s.UpdateFunc() }
+func (s *testStore) SetUser(u slack.User)        { s.User = u }
+func (s *testStore) SetChannel(ch slack.Channel) { s.Channel = ch }
+func (s *testStore) SetIM(im slack.IM)           { s.IM = im }
+func (s *testStore) RemoveChannel(id string) {
+	if s.Channel.ID == id {
+		s.Channel = slack.Channel{}
+	}
+}
 func (s *testStore) UserByID(id string) (slack.User, bool) {
 	if s.User.ID == id {
 		return s.User, true
@@ -88,3 +98,11 @@ func (s *testStore) IMByUserID(id string) (slack.IM, bool) {
 	}
 	return s.IM, false
 }
+
+// useAPI points web API calls at a test server until the returned func is
+// called
+func useAPI(url string) func() {
+	api := slack.SLACK_API
+	slack.SLACK_API = url + "/"
+	return func() { slack.SLACK_API = api }
+}
@@ -22,7 +22,8 @@ func (a *Adapter) parse(m *bot.Message, fns ...parser) error {
 
 func parseRoom(a *Adapter, m *bot.Message) error {
 	if len(m.Room) > 0 {
-		if m.Room[0] == 'C' || m.Room[0] == 'D' {
+		switch m.Room[0] {
+		case 'C', 'D', 'G':
 			return nil
 		}
 	}
@@ -76,26 +77,64 @@ func parseDM(a *Adapter, m *bot.Message) error {
 		return nil
 	}
 
-	_, _, imID, err := a.Client.OpenIMChannel(m.User)
+	im, _, _, err := a.Client.OpenConversation(&slack.OpenConversationParameters{
+		Users: []string{m.User},
+	})
 	if err != nil {
-		return fmt.Errorf("Couldn't open IM to User %s: %e", m.User, err)
+		return fmt.Errorf("Couldn't open IM to User %s: %v", m.User, err)
 	}
 
-	m.Room = imID
+	m.Room = im.ID
 	return nil
 }
 
-func parseParams(a *Adapter, m *bot.Message) error {
-	pm, ok := m.Params.(slack.PostMessageParameters)
+func parseThread(a *Adapter, m *bot.Message) error {
+	msg, ok := m.Envelope.(slack.Message)
 	if !ok {
-		return nil
+		return errors.New("Empty envelope provided")
+	}
+
+	ts := msg.ThreadTimestamp
+	if ts == "" {
+		ts = msg.Timestamp
 	}
 
-	pm.AsUser = true
-	if pm.User == "" {
-		pm.User = a.BotID
+	switch params := m.Params.(type) {
+	case nil:
+		m.Params = Thread{Timestamp: ts}
+	case Thread:
+		if params.Timestamp == "" {
+			params.Timestamp = ts
+		}
+		m.Params = params
+	case slack.PostMessageParameters:
+		if params.ThreadTimestamp == "" {
+			params.ThreadTimestamp = ts
+		}
+		m.Params = params
+	case Blocks:
+		if params.ThreadTimestamp == "" {
+			params.ThreadTimestamp = ts
+		}
+		m.Params = params
+	}
+
+	return nil
+}
+
+func parseParams(a *Adapter, m *bot.Message) error {
+	switch pm := m.Params.(type) {
+	case slack.PostMessageParameters:
+		pm.AsUser = true
+		if pm.User == "" {
+			pm.User = a.BotID
+		}
+		m.Params = pm
+	case Blocks:
+		if m.Text == "" {
+			m.Text = pm.fallback()
+		}
 	}
-	m.Params = pm
 
 	return nil
 }
@@ -1,6 +1,8 @@
 package slack
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/botopolis/bot"
@@ -22,6 +24,10 @@ func TestParseRoom(t *testing.T) {
 			In:  bot.Message{Room: "D1234"},
 			Out: bot.Message{Room: "D1234"},
 		},
+		{
+			In:  bot.Message{Room: "G1234"},
+			Out: bot.Message{Room: "G1234"},
+		},
 		{
 			In:  bot.Message{Room: "", Envelope: msg},
 			Out: bot.Message{Room: "C4321", Envelope: msg},
@@ -96,6 +102,25 @@ func TestParseDM(t *testing.T) {
 	}
 }
 
+func TestParseDM_open(t *testing.T) {
+	var path, users string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, users = r.URL.Path, r.FormValue("users")
+		w.Write([]byte(`{"ok":true,"channel":{"id":"D5678"}}`))
+	}))
+	defer server.Close()
+
+	defer useAPI(server.URL)()
+
+	a := New("xoxb-1")
+	a.Store = newTestStore()
+	m := bot.Message{User: "U5678"}
+	assert.NoError(t, parseDM(a, &m))
+	assert.Equal(t, "/conversations.open", path)
+	assert.Equal(t, "U5678", users)
+	assert.Equal(t, "D5678", m.Room)
+}
+
 func TestParseParams(t *testing.T) {
 	id := "B1234"
 	cases := []struct {
@@ -122,6 +147,14 @@ func TestParseParams(t *testing.T) {
 			In:  bot.Message{Params: slack.Message{}},
 			Out: bot.Message{Params: slack.Message{}},
 		},
+		{
+			In:  bot.Message{Params: Blocks{Blocks: []Block{Section{Text: Markdown("hi")}}}},
+			Out: bot.Message{Text: "hi", Params: Blocks{Blocks: []Block{Section{Text: Markdown("hi")}}}},
+		},
+		{
+			In:  bot.Message{Text: "hello", Params: Blocks{Blocks: []Block{Section{Text: Markdown("hi")}}}},
+			Out: bot.Message{Text: "hello", Params: Blocks{Blocks: []Block{Section{Text: Markdown("hi")}}}},
+		},
 	}
 
 	for _, c := range cases {
@@ -152,3 +185,50 @@ func TestParseChain(t *testing.T) {
 	a.parse(&in, parseDM, parseParams)
 	assert.Equal(t, out, in)
 }
+
+func TestParseThread(t *testing.T) {
+	msg := slack.Message{Msg: slack.Msg{Timestamp: "1.1"}}
+	threaded := slack.Message{Msg: slack.Msg{Timestamp: "2.2", ThreadTimestamp: "1.1"}}
+	cases := []struct {
+		In  bot.Message
+		Out bot.Message
+		Err bool
+	}{
+		{
+			In:  bot.Message{},
+			Err: true,
+		},
+		{
+			In:  bot.Message{Envelope: msg},
+			Out: bot.Message{Envelope: msg, Params: Thread{Timestamp: "1.1"}},
+		},
+		{
+			In:  bot.Message{Envelope: threaded},
+			Out: bot.Message{Envelope: threaded, Params: Thread{Timestamp: "1.1"}},
+		},
+		{
+			In:  bot.Message{Envelope: threaded, Params: Thread{Broadcast: true}},
+			Out: bot.Message{Envelope: threaded, Params: Thread{Timestamp: "1.1", Broadcast: true}},
+		},
+		{
+			In: bot.Message{Envelope: msg, Params: slack.PostMessageParameters{ReplyBroadcast: true}},
+			Out: bot.Message{Envelope: msg, Params: slack.PostMessageParameters{
+				ThreadTimestamp: "1.1",
+				ReplyBroadcast:  true,
+			}},
+		},
+		{
+			In:  bot.Message{Envelope: threaded, Params: Blocks{}},
+			Out: bot.Message{Envelope: threaded, Params: Blocks{ThreadTimestamp: "1.1"}},
+		},
+	}
+
+	for _, c := range cases {
+		err := parseThread(&Adapter{}, &c.In)
+		if c.Err {
+			assert.Error(t, err)
+		} else {
+			assert.Equal(t, c.Out, c.In)
+		}
+	}
+}
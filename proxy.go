@@ -1,23 +1,69 @@
 package slack
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"reflect"
+	"sync"
+	"time"
 
 	"github.com/botopolis/bot"
 	"github.com/nlopes/slack"
 )
 
+// reconnectMin and reconnectMax bound how long we wait between attempts
+// to reconnect when Slack won't let us back in.
+const (
+	reconnectMin = time.Second
+	reconnectMax = 5 * time.Minute
+)
+
+// errInvalidAuth is given to OnStateChange callbacks when Slack rejects
+// our credentials
+var errInvalidAuth = errors.New("invalid_auth")
+
+// errAckTimeout is returned when Slack didn't acknowledge a message sent
+// over RTM in time. It may or may not have been sent.
+var errAckTimeout = errors.New("slack: Timed out waiting for Slack to acknowledge message")
+
+// ackTimeout is how long we wait for Slack to acknowledge a message sent
+// over RTM. It's swapped out in tests.
+var ackTimeout = 10 * time.Second
+
+// sentRTM is called with each message sent over RTM. It's swapped out in
+// tests.
+var sentRTM = func(*slack.OutgoingMessage) {}
+
+// lookupRetry is how long we wait before looking up a user or channel
+// again after a lookup started, so unknown ones and failures don't cost a
+// request for every event.
+var lookupRetry = 10 * time.Minute
+
 type proxy struct {
 	*Adapter
-	RTM       *slack.RTM
-	formatter formatter
+	RTM *slack.RTM
+
+	stop chan struct{}
+	once sync.Once
+	// backoff spaces out reconnection attempts, and starts over once
+	// we're connected
+	backoff backoff
+
+	// acks are waiting for Slack to acknowledge messages sent over RTM,
+	// keyed by the messages' IDs. lookups are when each user or channel
+	// we've looked up may be looked up again.
+	mu      sync.Mutex
+	acks    map[int]chan sent
+	lookups map[string]time.Time
 }
 
 func newProxy(a *Adapter) *proxy {
 	return &proxy{
-		Adapter:   a,
-		RTM:       a.Client.NewRTM(),
-		formatter: formatter{a.Store},
+		Adapter: a,
+		RTM:     a.Client.NewRTM(),
+		stop:    make(chan struct{}),
+		backoff: backoff{Min: reconnectMin, Max: reconnectMax},
 	}
 }
 
@@ -41,18 +87,135 @@ func (p *proxy) onConnect(ev *slack.ConnectedEvent) {
 	}
 }
 
-func (p *proxy) Send(m bot.Message) error {
-	if m.Params == nil {
-		p.RTM.SendMessage(p.RTM.NewOutgoingMessage(m.Text, m.Room))
-		return nil
+func (p *proxy) Send(m bot.Message) (MessageRef, error) {
+	switch params := m.Params.(type) {
+	case nil:
+		return p.sendRTM(p.RTM.NewOutgoingMessage(m.Text, m.Room))
+	case Thread:
+		msg := p.RTM.NewOutgoingMessage(m.Text, m.Room)
+		msg.ThreadTimestamp = params.Timestamp
+		msg.ThreadBroadcast = params.Broadcast
+		return p.sendRTM(msg)
+	case slack.PostMessageParameters:
+		channel, ts, err := p.Client.PostMessage(m.Room, m.Text, params)
+		return MessageRef{Channel: channel, Timestamp: ts}, err
+	case Blocks:
+		return p.postBlocks(m.Room, m.Text, params)
+	case ephemeral:
+		return p.postEphemeral(m, params.Params)
+	}
+
+	return MessageRef{}, nil
+}
+
+// sendRTM sends a message over RTM, and waits for Slack to acknowledge it
+// with the message's timestamp or reject it
+func (p *proxy) sendRTM(msg *slack.OutgoingMessage) (MessageRef, error) {
+	ack := make(chan sent, 1)
+	p.mu.Lock()
+	if p.acks == nil {
+		p.acks = make(map[int]chan sent)
+	}
+	p.acks[msg.ID] = ack
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		delete(p.acks, msg.ID)
+		p.mu.Unlock()
+	}()
+
+	p.RTM.SendMessage(msg)
+	sentRTM(msg)
+	select {
+	case s := <-ack:
+		if s.err != nil {
+			return MessageRef{}, s.err
+		}
+		return MessageRef{Channel: msg.Channel, Timestamp: s.ref.Timestamp}, nil
+	case <-time.After(ackTimeout):
+		return MessageRef{}, errAckTimeout
+	}
+}
+
+// splitAcks takes acknowledgements out of RTM's events as they arrive,
+// passing on everything else. Sends don't wait behind events the robot
+// hasn't taken yet, or for Forward to finish running callbacks which
+// send messages themselves.
+func (p *proxy) splitAcks(in <-chan slack.RTMEvent) <-chan slack.RTMEvent {
+	out := make(chan slack.RTMEvent)
+	go func() {
+		defer close(out)
+		var backlog []slack.RTMEvent
+		for in != nil || len(backlog) > 0 {
+			var (
+				next slack.RTMEvent
+				send chan<- slack.RTMEvent
+			)
+			if len(backlog) > 0 {
+				next, send = backlog[0], out
+			}
+
+			select {
+			case ev, ok := <-in:
+				if !ok {
+					in = nil
+				} else if !p.acked(ev) {
+					backlog = append(backlog, ev)
+				}
+			case send <- next:
+				backlog = backlog[1:]
+			}
+		}
+	}()
+	return out
+}
+
+// acked hands Slack's answer to a message sent over RTM to whoever sent
+// it, reporting whether ev was one. Errors don't say which message they
+// are about, but Slack answers in order, so they go to the oldest message
+// waiting.
+func (p *proxy) acked(ev slack.RTMEvent) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var (
+		id int
+		s  sent
+	)
+	switch data := ev.Data.(type) {
+	case *slack.AckMessage:
+		id, s.ref.Timestamp = data.ReplyTo, data.Timestamp
+	case *slack.AckErrorEvent:
+		id, s.err = p.oldestAck(), data
+	case *slack.RateLimitEvent:
+		id, s.err = p.oldestAck(), data
+	default:
+		return false
 	}
 
-	if pm, ok := m.Params.(slack.PostMessageParameters); ok {
-		_, _, err := p.Client.PostMessage(m.Room, m.Text, pm)
-		return err
+	ack, ok := p.acks[id]
+	if !ok {
+		if s.err != nil {
+			p.Robot.Logger.Errorf("slack: Message not sent: %s", s.err)
+		}
+		return true
 	}
+	delete(p.acks, id)
+	ack <- s
+	return true
+}
 
-	return nil
+// oldestAck returns the ID of the oldest message waiting for Slack's
+// answer, or 0 if there are none. It expects the caller to hold p.mu.
+func (p *proxy) oldestAck() int {
+	oldest := 0
+	for id := range p.acks {
+		if oldest == 0 || id < oldest {
+			oldest = id
+		}
+	}
+	return oldest
 }
 
 func (p *proxy) React(m bot.Message) error {
@@ -62,46 +225,200 @@ func (p *proxy) React(m bot.Message) error {
 }
 
 func (p *proxy) SetTopic(room, topic string) error {
-	_, err := p.Client.SetChannelTopic(room, topic)
+	_, err := p.Client.SetTopicOfConversation(room, topic)
 	return err
 }
 
 func (p *proxy) Connect() chan bot.Message {
-	go p.RTM.ManageConnection()
+	go p.ManageConnection()
 	ch := make(chan bot.Message, 32)
-	go p.Forward(p.RTM.IncomingEvents, ch)
+	go p.Forward(p.splitAcks(p.RTM.IncomingEvents), ch)
 	return ch
 }
 
+// ManageConnection keeps RTM running until Disconnect is called. RTM
+// reconnects by itself when the connection drops, but gives up when
+// Slack rejects our credentials, so we start it again after a while.
+func (p *proxy) ManageConnection() {
+	for {
+		p.RTM.ManageConnection()
+		if p.stopped() {
+			return
+		}
+
+		wait := p.backoff.Duration()
+		p.Robot.Logger.Errorf("slack: Unable to connect, retrying in %s", wait)
+		select {
+		case <-p.stop:
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
 func (p *proxy) Disconnect() {
+	p.halt()
 	if p.RTM != nil {
 		p.RTM.Disconnect()
 	}
 }
 
+// halt signals any connection loop that we're shutting down
+func (p *proxy) halt() {
+	if p.stop != nil {
+		p.once.Do(func() { close(p.stop) })
+	}
+}
+
+func (p *proxy) stopped() bool {
+	select {
+	case <-p.stop:
+		return true
+	default:
+		return false
+	}
+}
+
 func (p *proxy) Forward(in <-chan slack.RTMEvent, out chan<- bot.Message) {
 	defer close(out)
 	for msg := range in {
 		switch ev := msg.Data.(type) {
 		case *slack.HelloEvent:
+		case *slack.ConnectingEvent:
+			p.setState(Connecting, nil)
 		case *slack.ConnectedEvent:
+			p.backoff.Reset()
 			p.onConnect(ev)
 			p.Robot.Logger.Debugf("slack: Connected as %s: %d", ev.Info.User.ID, ev.ConnectionCount)
+			p.setState(Connected, nil)
+		case *slack.DisconnectedEvent:
+			if ev.Intentional {
+				p.setState(Disconnected, nil)
+			} else {
+				p.setState(Connecting, nil)
+			}
 		case *slack.MessageEvent:
 			out <- p.translate(ev)
+		case *slack.ChannelCreatedEvent,
+			*slack.ChannelRenameEvent,
+			*slack.ChannelArchiveEvent,
+			*slack.ChannelDeletedEvent,
+			*slack.TeamJoinEvent,
+			*slack.UserChangeEvent,
+			*slack.IMCreatedEvent,
+			*slack.MemberJoinedChannelEvent:
+			p.updateStore(ev)
 		case *slack.RTMError:
 			p.Robot.Logger.Errorf("slack: RTM Error: %s", ev.Error())
 		case *slack.ConnectionErrorEvent:
-			p.Robot.Logger.Error("slack: Connection Error: %s", ev.Error())
+			p.Robot.Logger.Errorf("slack: Connection Error: %s", ev.Error())
+			p.setState(Connecting, ev)
 		case *slack.InvalidAuthEvent:
 			p.Robot.Logger.Error("slack: Invalid Credentials")
-			return
+			p.setState(Disconnected, errInvalidAuth)
+		}
+	}
+}
+
+// startLookup reports whether a user or channel we haven't seen should be
+// looked up now: not while a lookup is running, or for lookupRetry after
+// one failed
+func (p *proxy) startLookup(id string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	if now.Before(p.lookups[id]) {
+		return false
+	}
+	if p.lookups == nil {
+		p.lookups = make(map[string]time.Time)
+	}
+	p.lookups[id] = now.Add(lookupRetry)
+	return true
+}
+
+// fetchUser looks up a user we haven't seen yet, such as someone from
+// another organization posting in a shared channel. It runs outside of
+// Forward so messages aren't held up, and adds the user to the store for
+// the messages that follow.
+func (p *proxy) fetchUser(id string) {
+	user, err := p.Client.GetUserInfo(id)
+	if err != nil {
+		p.warnf("slack: Unable to fetch user %s: %v", id, err)
+		return
+	}
+
+	p.Store.SetUser(*user)
+	p.mu.Lock()
+	delete(p.lookups, id)
+	p.mu.Unlock()
+}
+
+// fetchChannel looks up a channel we haven't seen yet, such as one we've
+// just been invited to. Like fetchUser, it runs outside of Forward.
+func (p *proxy) fetchChannel(id string) {
+	ch, err := p.Client.GetConversationInfo(id, false)
+	if err != nil {
+		p.warnf("slack: Unable to fetch channel %s: %v", id, err)
+		return
+	}
+
+	p.Store.SetChannel(*ch)
+	p.mu.Lock()
+	delete(p.lookups, id)
+	p.mu.Unlock()
+}
+
+// updateStore keeps the Store in sync with changes Slack tells us about
+// after we've connected.
+func (p *proxy) updateStore(ev interface{}) {
+	switch ev := ev.(type) {
+	case *slack.ChannelCreatedEvent:
+		ch := slack.Channel{IsChannel: ev.Channel.IsChannel}
+		ch.ID = ev.Channel.ID
+		ch.Name = ev.Channel.Name
+		ch.Creator = ev.Channel.Creator
+		ch.Created = slack.JSONTime(ev.Channel.Created)
+		p.Store.SetChannel(ch)
+	case *slack.ChannelRenameEvent:
+		ch, _ := p.Store.ChannelByID(ev.Channel.ID)
+		ch.ID = ev.Channel.ID
+		ch.Name = ev.Channel.Name
+		p.Store.SetChannel(ch)
+	case *slack.ChannelArchiveEvent:
+		if ch, ok := p.Store.ChannelByID(ev.Channel); ok {
+			ch.IsArchived = true
+			p.Store.SetChannel(ch)
+		}
+	case *slack.ChannelDeletedEvent:
+		p.Store.RemoveChannel(ev.Channel)
+	case *slack.TeamJoinEvent:
+		p.Store.SetUser(ev.User)
+	case *slack.UserChangeEvent:
+		p.Store.SetUser(ev.User)
+	case *slack.IMCreatedEvent:
+		im := slack.IM{IsIM: true, User: ev.User}
+		im.ID = ev.Channel.ID
+		im.Created = slack.JSONTime(ev.Channel.Created)
+		p.Store.SetIM(im)
+	case *slack.MemberJoinedChannelEvent:
+		// Members isn't kept up to date, as conversations.list doesn't
+		// list them anyway: we only make sure we know the channel
+		if _, ok := p.Store.ChannelByID(ev.Channel); !ok && p.startLookup(ev.Channel) {
+			go p.fetchChannel(ev.Channel)
 		}
 	}
 }
 
 func (p *proxy) translate(ev *slack.MessageEvent) bot.Message {
-	user, _ := p.Store.UserByID(ev.User)
+	user, ok := p.Store.UserByID(ev.User)
+	if !ok && ev.User != "" {
+		// Until we know who they are, refer to them by ID
+		user.Name = ev.User
+		if p.startLookup(ev.User) {
+			go p.fetchUser(ev.User)
+		}
+	}
 	channel, _ := p.Store.ChannelByID(ev.Channel)
 
 	// Prepend the bots name whenever a direct message is parsed
@@ -112,7 +429,7 @@ func (p *proxy) translate(ev *slack.MessageEvent) bot.Message {
 	m := bot.Message{
 		User:     user.Name,
 		Room:     channel.Name,
-		Text:     p.formatter.Format(ev),
+		Text:     formatter{p.Store}.Format(ev),
 		Topic:    ev.Topic,
 		Envelope: slack.Message(*ev),
 	}
@@ -130,3 +447,29 @@ func (p *proxy) translate(ev *slack.MessageEvent) bot.Message {
 
 	return m
 }
+
+// rtmEvent decodes an event delivered outside of the RTM socket into the
+// same struct RTM would have produced, so it can go through Forward.
+func rtmEvent(raw json.RawMessage) (slack.RTMEvent, error) {
+	var head struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(raw, &head); err != nil {
+		return slack.RTMEvent{}, err
+	}
+
+	v, ok := slack.EventMapping[head.Type]
+	if !ok {
+		return slack.RTMEvent{}, fmt.Errorf("unmapped event %q", head.Type)
+	}
+
+	ev := reflect.New(reflect.TypeOf(v)).Interface()
+	if err := json.Unmarshal(raw, ev); err != nil {
+		return slack.RTMEvent{}, err
+	}
+	if msg, ok := ev.(*slack.MessageEvent); ok {
+		legacyFile(raw, msg)
+	}
+
+	return slack.RTMEvent{Type: head.Type, Data: ev}, nil
+}
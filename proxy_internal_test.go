@@ -1,7 +1,10 @@
 package slack
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/botopolis/bot"
 	"github.com/botopolis/bot/mock"
@@ -73,10 +76,9 @@ func TestProxy(t *testing.T) {
 	}
 
 	store := newTestStore()
-	p := proxy{
-		Adapter:   New(""),
-		formatter: formatter{store},
-	}
+	store.User.ID = user
+	p := proxy{Adapter: New("")}
+	p.Adapter.Store = store
 	for _, c := range proxyTestCases {
 		in := make(chan slack.RTMEvent, 2)
 		out := make(chan bot.Message, 2)
@@ -103,9 +105,10 @@ func TestProxyForward_connect(t *testing.T) {
 		assert.Equal(t, info, i)
 		run = true
 	}
-	p := proxy{Adapter: New("")}
+	p := proxy{Adapter: New(""), backoff: backoff{Min: time.Second, Max: time.Minute}}
 	p.Adapter.Store = store
 	p.Load(bot.New(mock.NewChat()))
+	p.backoff.Duration()
 
 	ch := make(chan slack.RTMEvent, 2)
 	ch <- slack.RTMEvent{Data: &slack.ConnectedEvent{Info: info}}
@@ -115,21 +118,176 @@ func TestProxyForward_connect(t *testing.T) {
 	assert.Equal(t, info.User.ID, p.Adapter.BotID)
 	assert.Equal(t, info.User.Name, p.Adapter.Username())
 	assert.True(t, run)
+	assert.Equal(t, time.Second, p.backoff.Duration(), "backoff starts over")
 }
 
 func TestProxyForward_invalidAuth(t *testing.T) {
-	done := make(chan bool)
 	p := proxy{Adapter: New("")}
 	p.Load(&bot.Robot{Logger: mock.NewLogger()})
 
-	// Send the event, explicitly don't close the channel
+	var changes []StateChange
+	p.OnStateChange(func(c StateChange) { changes = append(changes, c) })
+
 	ch := make(chan slack.RTMEvent, 2)
+	ch <- slack.RTMEvent{Data: &slack.ConnectingEvent{Attempt: 1}}
 	ch <- slack.RTMEvent{Data: &slack.InvalidAuthEvent{}}
+	close(ch)
+	p.Forward(ch, make(chan bot.Message))
+
+	state, _ := p.State()
+	assert.Equal(t, Disconnected, state)
+	assert.Len(t, changes, 2)
+	assert.Equal(t, Connecting, changes[1].From)
+	assert.Equal(t, errInvalidAuth, changes[1].Err)
+}
+
+func TestProxyForward_store(t *testing.T) {
+	store := newMemoryStore(&slack.Client{})
+	store.Load(slackUserInfo())
+	p := proxy{Adapter: &Adapter{Store: store}}
+
+	ch := make(chan slack.RTMEvent, 10)
+	ch <- slack.RTMEvent{Data: &slack.ChannelCreatedEvent{
+		Channel: slack.ChannelCreatedInfo{ID: "C4321", Name: "new", IsChannel: true},
+	}}
+	ch <- slack.RTMEvent{Data: &slack.ChannelRenameEvent{
+		Channel: slack.ChannelRenameInfo{ID: "C4321", Name: "renamed"},
+	}}
+	ch <- slack.RTMEvent{Data: &slack.ChannelArchiveEvent{Channel: "C4321"}}
+	ch <- slack.RTMEvent{Data: &slack.ChannelDeletedEvent{Channel: "C1234"}}
+	ch <- slack.RTMEvent{Data: &slack.TeamJoinEvent{User: slack.User{ID: "U4321", Name: "Joan"}}}
+	ch <- slack.RTMEvent{Data: &slack.UserChangeEvent{User: slack.User{ID: "U1234", Name: "Jeanne"}}}
+	ch <- slack.RTMEvent{Data: &slack.IMCreatedEvent{
+		User:    "U4321",
+		Channel: slack.ChannelCreatedInfo{ID: "D4321"},
+	}}
+	ch <- slack.RTMEvent{Data: &slack.MemberJoinedChannelEvent{User: "U4321", Channel: "C4321"}}
+	close(ch)
+	p.Forward(ch, make(chan bot.Message))
+
+	channel, ok := store.ChannelByName("renamed")
+	assert.True(t, ok)
+	assert.Equal(t, "C4321", channel.ID)
+	assert.True(t, channel.IsArchived)
+	assert.Empty(t, channel.Members)
+	_, ok = store.ChannelByName("new")
+	assert.False(t, ok)
+	_, ok = store.ChannelByID("C1234")
+	assert.False(t, ok)
+
+	_, ok = store.UserByName("Joan")
+	assert.True(t, ok)
+	user, _ := store.UserByID("U1234")
+	assert.Equal(t, "Jeanne", user.Name)
+
+	im, ok := store.IMByUserID("U4321")
+	assert.True(t, ok)
+	assert.Equal(t, "D4321", im.ID)
+}
+
+// userStore tells the test when a user is added
+type userStore struct {
+	*testStore
+	set chan slack.User
+}
+
+func (s userStore) SetUser(u slack.User) {
+	s.testStore.SetUser(u)
+	s.set <- u
+}
 
-	go func() {
-		p.Forward(ch, make(chan bot.Message))
-		done <- true
-	}()
+func TestProxyTranslate_unknownUser(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ok":true,"user":{"id":"W1234","name":"partner"}}`))
+	}))
+	defer server.Close()
+
+	defer useAPI(server.URL)()
+
+	store := userStore{newTestStore(), make(chan slack.User, 1)}
+	store.Channel.ID = "C1234"
+	store.Channel.Name = "shared"
+	p := proxy{Adapter: New("xoxb-1")}
+	p.Adapter.Store = store
 
-	assert.True(t, <-done)
+	ev := slack.MessageEvent{Msg: slack.Msg{User: "W1234", Channel: "C1234"}}
+	m := p.translate(&ev)
+	assert.Equal(t, "W1234", m.User)
+	assert.Equal(t, "shared", m.Room)
+
+	assert.Equal(t, "W1234", (<-store.set).ID)
+	m = p.translate(&ev)
+	assert.Equal(t, "partner", m.User)
+}
+
+// channelStore tells the test when a channel is added
+type channelStore struct {
+	*testStore
+	set chan slack.Channel
+}
+
+func (s channelStore) SetChannel(ch slack.Channel) {
+	s.testStore.SetChannel(ch)
+	s.set <- ch
+}
+
+func TestProxyForward_unknownChannel(t *testing.T) {
+	release := make(chan struct{})
+	requests := make(chan string, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- r.URL.Path
+		<-release
+		assert.Equal(t, "/conversations.info", r.URL.Path)
+		assert.Equal(t, "G4321", r.FormValue("channel"))
+		w.Write([]byte(`{"ok":true,"channel":{"id":"G4321","name":"invited"}}`))
+	}))
+	defer server.Close()
+
+	defer useAPI(server.URL)()
+
+	store := channelStore{newTestStore(), make(chan slack.Channel, 1)}
+	p := proxy{Adapter: New("xoxb-1")}
+	p.Adapter.Store = store
+
+	ch := make(chan slack.RTMEvent, 2)
+	ch <- slack.RTMEvent{Data: &slack.MemberJoinedChannelEvent{User: "U1234", Channel: "G4321"}}
+	ch <- slack.RTMEvent{Data: &slack.MemberJoinedChannelEvent{User: "U4321", Channel: "G4321"}}
+	close(ch)
+	// Forward isn't held up by the lookup
+	p.Forward(ch, make(chan bot.Message))
+	close(release)
+
+	channel := <-store.set
+	assert.Len(t, requests, 1, "looked up once")
+	assert.Equal(t, "G4321", channel.ID)
+	assert.Equal(t, "invited", channel.Name)
+	assert.Empty(t, channel.Members)
+}
+
+func TestProxyStartLookup(t *testing.T) {
+	p := proxy{}
+	assert.True(t, p.startLookup("W1234"))
+	assert.False(t, p.startLookup("W1234"), "already looking up")
+	assert.True(t, p.startLookup("W5678"))
+
+	p.lookups["W1234"] = time.Now()
+	assert.True(t, p.startLookup("W1234"), "retried after a while")
+}
+
+func TestProxySetTopic(t *testing.T) {
+	var path, channel string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, channel = r.URL.Path, r.FormValue("channel")
+		w.Write([]byte(`{"ok":true,"channel":{"id":"C1234"}}`))
+	}))
+	defer server.Close()
+
+	defer useAPI(server.URL)()
+
+	p := proxy{Adapter: New("xoxb-1")}
+	for _, room := range []string{"C1234", "G1234"} {
+		assert.NoError(t, p.SetTopic(room, "generally awesome"))
+		assert.Equal(t, "/conversations.setTopic", path)
+		assert.Equal(t, room, channel)
+	}
 }
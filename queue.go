@@ -0,0 +1,185 @@
+package slack
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/botopolis/bot"
+	"github.com/nlopes/slack"
+)
+
+// errQueueClosed is returned when sending after the adapter was unloaded
+var errQueueClosed = errors.New("slack: Message queue is closed")
+
+// queuedMessage is called with each message once it's queued. It's
+// swapped out in tests.
+var queuedMessage = func(bot.Message) {}
+
+// queue paces outgoing messages so Slack doesn't drop them. Messages to
+// the same room are sent in order, no faster than Adapter.ChannelRate,
+// and messages overall no faster than Adapter.GlobalRate. Everything
+// else is handed straight to the underlying transport.
+type queue struct {
+	transport
+	a *Adapter
+
+	mu      sync.Mutex
+	wg      sync.WaitGroup
+	closed  bool
+	pending map[string][]*queued
+	last    map[string]time.Time
+	next    time.Time
+
+	// stop is closed when Disconnect gives up on flushing the queue
+	stop chan struct{}
+}
+
+type queued struct {
+	m    bot.Message
+	done chan sent
+}
+
+// sent is the outcome of sending a queued message
+type sent struct {
+	ref MessageRef
+	err error
+}
+
+func newQueue(a *Adapter, t transport) *queue {
+	return &queue{
+		transport: t,
+		a:         a,
+		pending:   make(map[string][]*queued),
+		last:      make(map[string]time.Time),
+		stop:      make(chan struct{}),
+	}
+}
+
+// Send queues the message and waits until it has been sent
+func (q *queue) Send(m bot.Message) (MessageRef, error) {
+	job := &queued{m: m, done: make(chan sent, 1)}
+
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return MessageRef{}, errQueueClosed
+	}
+	q.pending[m.Room] = append(q.pending[m.Room], job)
+	if len(q.pending[m.Room]) == 1 {
+		q.wg.Add(1)
+		go q.work(m.Room)
+	}
+	q.mu.Unlock()
+
+	queuedMessage(m)
+
+	s := <-job.done
+	return s.ref, s.err
+}
+
+// Disconnect waits up to Adapter.FlushTimeout for queued messages to be
+// sent before disconnecting. Messages still queued after that fail.
+func (q *queue) Disconnect() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+
+	flushed := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(flushed)
+	}()
+
+	select {
+	case <-flushed:
+	case <-time.After(q.a.FlushTimeout):
+		q.a.warnf("slack: Gave up waiting for queued messages after %s", q.a.FlushTimeout)
+		if !q.stopped() {
+			close(q.stop)
+		}
+	}
+
+	q.transport.Disconnect()
+}
+
+// mount passes the robot through to transports which need its router
+func (q *queue) mount(r *bot.Robot) {
+	if m, ok := q.transport.(interface{ mount(*bot.Robot) }); ok {
+		m.mount(r)
+	}
+}
+
+// work sends everything queued for a room, one at a time. It exits once
+// the room's queue is empty; Send starts a new one when needed.
+func (q *queue) work(room string) {
+	defer q.wg.Done()
+	for {
+		q.mu.Lock()
+		job := q.pending[room][0]
+		wait := time.Until(q.last[room].Add(q.a.ChannelRate))
+		q.mu.Unlock()
+
+		var ref MessageRef
+		err := errQueueClosed
+		if !q.stopped() && pause(wait, q.stop) && q.reserve() {
+			ref, err = q.send(job.m)
+		}
+
+		q.mu.Lock()
+		q.last[room] = time.Now()
+		q.pending[room] = q.pending[room][1:]
+		empty := len(q.pending[room]) == 0
+		if empty {
+			delete(q.pending, room)
+		}
+		q.mu.Unlock()
+
+		job.done <- sent{ref, err}
+		if empty {
+			return
+		}
+	}
+}
+
+// stopped reports whether Disconnect gave up on the queue
+func (q *queue) stopped() bool {
+	select {
+	case <-q.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// reserve waits for the next free slot under the global rate, reporting
+// false if the queue was given up on meanwhile
+func (q *queue) reserve() bool {
+	q.mu.Lock()
+	now := time.Now()
+	slot := q.next
+	if slot.Before(now) {
+		slot = now
+	}
+	q.next = slot.Add(q.a.GlobalRate)
+	q.mu.Unlock()
+
+	return pause(slot.Sub(now), q.stop)
+}
+
+// send retries messages Slack rejected for being sent too quickly, until
+// the queue is given up on
+func (q *queue) send(m bot.Message) (ref MessageRef, err error) {
+	err = retryUntil(q.stop, q.a.warnf, func() error {
+		ref, err = q.transport.Send(m)
+		if _, ok := err.(*slack.RateLimitEvent); ok {
+			// RTM asked us to slow down
+			return rateLimited{err}
+		}
+		if err != nil && (err.Error() == "rate_limited" || err.Error() == "ratelimited") {
+			return rateLimited{err}
+		}
+		return err
+	})
+	return ref, err
+}
@@ -0,0 +1,219 @@
+package slack
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/botopolis/bot"
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestQueue() (*queue, *testProxy) {
+	proxy := newTestProxy()
+	a := &Adapter{ChannelRate: time.Second, GlobalRate: time.Millisecond, FlushTimeout: time.Second}
+	q := newQueue(a, proxy)
+	a.proxy = q
+	return q, proxy
+}
+
+// stubPause records pauses instead of waiting, until the returned func is
+// called
+func stubPause(record func(time.Duration)) func() {
+	original := pause
+	pause = func(d time.Duration, done <-chan struct{}) bool {
+		if d > 0 {
+			record(d)
+		}
+		return true
+	}
+	return func() { pause = original }
+}
+
+// watchQueue reports the text of each message once it's queued, until
+// the returned func is called
+func watchQueue() (<-chan string, func()) {
+	queued := make(chan string)
+	queuedMessage = func(m bot.Message) { queued <- m.Text }
+	return queued, func() { queuedMessage = func(bot.Message) {} }
+}
+
+func TestQueue_order(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		sent   []string
+		waited []time.Duration
+	)
+	defer stubPause(func(d time.Duration) {
+		mu.Lock()
+		waited = append(waited, d)
+		mu.Unlock()
+	})()
+
+	queued, done := watchQueue()
+	defer done()
+
+	q, proxy := newTestQueue()
+	release := make(chan bool)
+	proxy.SendFunc = func(m bot.Message) error {
+		<-release
+		mu.Lock()
+		sent = append(sent, m.Text)
+		mu.Unlock()
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	for _, text := range []string{"one", "two", "three"} {
+		wg.Add(1)
+		go func(text string) {
+			defer wg.Done()
+			_, err := q.Send(bot.Message{Room: "C1234", Text: text})
+			assert.NoError(t, err)
+		}(text)
+		// wait for the message to be queued before sending the next one
+		assert.Equal(t, text, <-queued)
+	}
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, []string{"one", "two", "three"}, sent)
+	// The second and third messages wait for the channel to be free
+	var paced int
+	for _, d := range waited {
+		if d > 900*time.Millisecond {
+			paced++
+		}
+	}
+	assert.Equal(t, 2, paced)
+}
+
+func TestQueue_rooms(t *testing.T) {
+	q, _ := newTestQueue()
+	q.a.GlobalRate = 0
+
+	start := time.Now()
+	_, err := q.Send(bot.Message{Room: "C1234", Text: "foo"})
+	assert.NoError(t, err)
+	ref, err := q.Send(bot.Message{Room: "C4321", Text: "foo"})
+	assert.NoError(t, err)
+	assert.Equal(t, MessageRef{Channel: "C4321", Timestamp: "1.1"}, ref)
+	assert.True(t, time.Since(start) < q.a.ChannelRate)
+}
+
+func TestQueue_rateLimited(t *testing.T) {
+	var waited []time.Duration
+	defer stubPause(func(d time.Duration) { waited = append(waited, d) })()
+
+	q, proxy := newTestQueue()
+	q.a.GlobalRate = 0
+	errs := []error{
+		&slack.RateLimitedError{RetryAfter: 3 * time.Second},
+		errors.New("rate_limited"),
+		&slack.RateLimitEvent{},
+		nil,
+	}
+	proxy.SendFunc = func(bot.Message) error {
+		err := errs[0]
+		errs = errs[1:]
+		return err
+	}
+
+	_, err := q.Send(bot.Message{Room: "C1234", Text: "foo"})
+	assert.NoError(t, err)
+	assert.Len(t, waited, 3)
+	assert.Equal(t, []time.Duration{3 * time.Second, time.Second}, waited[:2])
+	// Without a Retry-After we back off
+	assert.True(t, waited[2] >= time.Second && waited[2] <= 2*time.Second, waited[2].String())
+}
+
+func TestRetry_attempts(t *testing.T) {
+	var waited []time.Duration
+	defer stubPause(func(d time.Duration) { waited = append(waited, d) })()
+
+	var calls int
+	limited := &slack.RateLimitedError{RetryAfter: time.Second}
+	err := retry(func(string, ...interface{}) {}, func() error {
+		calls++
+		return limited
+	})
+	assert.Equal(t, limited, err)
+	assert.Equal(t, retryAttempts, calls)
+	assert.Len(t, waited, retryAttempts-1)
+}
+
+func TestQueue_rateLimitedGivesUp(t *testing.T) {
+	defer stubPause(func(time.Duration) {})()
+
+	q, proxy := newTestQueue()
+	q.a.GlobalRate = 0
+	var calls int
+	limited := errors.New("ratelimited")
+	proxy.SendFunc = func(bot.Message) error {
+		calls++
+		return limited
+	}
+
+	_, err := q.Send(bot.Message{Room: "C1234", Text: "foo"})
+	assert.Equal(t, limited, err, "Slack's error is kept")
+	assert.Equal(t, retryAttempts, calls)
+}
+
+func TestQueue_disconnect(t *testing.T) {
+	queued, done := watchQueue()
+	defer done()
+
+	q, proxy := newTestQueue()
+	q.a.FlushTimeout = 10 * time.Millisecond
+	release := make(chan bool)
+	proxy.SendFunc = func(bot.Message) error {
+		<-release
+		return nil
+	}
+
+	go q.Send(bot.Message{Room: "C1234", Text: "foo"})
+	<-queued
+
+	start := time.Now()
+	q.Disconnect()
+	assert.True(t, time.Since(start) >= q.a.FlushTimeout)
+	_, err := q.Send(bot.Message{Room: "C1234", Text: "bar"})
+	assert.Equal(t, errQueueClosed, err)
+	close(release)
+}
+
+func TestQueue_disconnectRateLimited(t *testing.T) {
+	original := pause
+	defer func() { pause = original }()
+	// Rate limits last until the queue is given up on
+	pause = func(d time.Duration, done <-chan struct{}) bool {
+		if d <= 0 {
+			return true
+		}
+		<-done
+		return false
+	}
+
+	queued, done := watchQueue()
+	defer done()
+
+	q, proxy := newTestQueue()
+	q.a.FlushTimeout = 10 * time.Millisecond
+	limited := &slack.RateLimitedError{RetryAfter: time.Minute}
+	proxy.SendFunc = func(bot.Message) error { return limited }
+
+	errs := map[string]chan error{"one": make(chan error, 1), "two": make(chan error, 1)}
+	for _, text := range []string{"one", "two"} {
+		go func(text string) {
+			_, err := q.Send(bot.Message{Room: "C1234", Text: text})
+			errs[text] <- err
+		}(text)
+		<-queued
+	}
+
+	q.Disconnect()
+	assert.Equal(t, limited, <-errs["one"])
+	assert.Equal(t, errQueueClosed, <-errs["two"])
+}
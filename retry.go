@@ -0,0 +1,71 @@
+package slack
+
+import (
+	"time"
+
+	"github.com/nlopes/slack"
+)
+
+// retryAttempts is how many times retry calls a function Slack keeps
+// rate limiting before giving up
+const retryAttempts = 5
+
+// pause waits for d, or until done is closed, reporting whether it waited
+// the whole time. It's swapped out in tests.
+var pause = func(d time.Duration, done <-chan struct{}) bool {
+	if d <= 0 {
+		return true
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-done:
+		return false
+	}
+}
+
+// rateLimited wraps an error meaning Slack rate limited us without saying
+// for how long. retryUntil backs off from it like a RateLimitedError, and
+// returns the original error once it gives up.
+type rateLimited struct{ err error }
+
+func (e rateLimited) Error() string { return e.err.Error() }
+
+// retry calls fn until it returns anything other than a rate limit error,
+// waiting as long as Slack's Retry-After header asks in between.
+func retry(logf func(string, ...interface{}), fn func() error) error {
+	return retryUntil(nil, logf, fn)
+}
+
+// retryUntil is retry, giving up early once done is closed. Rate limits
+// which don't say how long to wait are backed off from exponentially.
+// Either way it stops after retryAttempts calls, returning the last error.
+func retryUntil(done <-chan struct{}, logf func(string, ...interface{}), fn func() error) error {
+	b := backoff{Min: time.Second, Max: time.Minute}
+	for attempt := 1; ; attempt++ {
+		var wait time.Duration
+		err := fn()
+		switch rl := err.(type) {
+		case *slack.RateLimitedError:
+			wait = rl.RetryAfter
+		case rateLimited:
+			err = rl.err
+		default:
+			return err
+		}
+		if attempt == retryAttempts {
+			return err
+		}
+
+		if wait <= 0 {
+			wait = b.Duration()
+		}
+		logf("slack: Rate limited, retrying in %s", wait)
+		if !pause(wait, done) {
+			return err
+		}
+	}
+}
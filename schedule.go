@@ -0,0 +1,125 @@
+package slack
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/botopolis/bot"
+	"github.com/botopolis/slack/internal/webapi"
+	"github.com/nlopes/slack"
+)
+
+// ScheduledMessage is a message Slack holds on to, to post later on (see
+// Adapter.Schedule)
+type ScheduledMessage struct {
+	ID      string
+	Channel string
+	Text    string
+	PostAt  time.Time
+	Created time.Time
+}
+
+// Schedule has Slack post a message at the given time, even if the bot
+// isn't running by then. The room and params are handled as in Send. It
+// returns the scheduled message's ID, to cancel it with Unschedule.
+func (a *Adapter) Schedule(m bot.Message, at time.Time) (string, error) {
+	if emptyMessage(m) {
+		return "", errors.New("No message to schedule")
+	}
+
+	if err := a.parse(&m, parseRoom, parseParams); err != nil {
+		return "", err
+	}
+
+	if m.Room == "" {
+		return "", errors.New("No room provided")
+	}
+
+	values, err := messageValues(m)
+	if err != nil {
+		return "", err
+	}
+	values.Set("post_at", strconv.FormatInt(at.Unix(), 10))
+
+	var resp struct {
+		slack.SlackResponse
+		ID string `json:"scheduled_message_id"`
+	}
+	err = retry(a.warnf, func() error {
+		return webapi.Form(a.token, "chat.scheduleMessage", values, &resp)
+	})
+	return resp.ID, err
+}
+
+// ScheduledMessages lists the messages the bot has scheduled which have
+// yet to be posted, in the given room or everywhere if it's empty
+func (a *Adapter) ScheduledMessages(room string) ([]ScheduledMessage, error) {
+	values := url.Values{}
+	if room != "" {
+		m := bot.Message{Room: room}
+		if err := parseRoom(a, &m); err != nil {
+			return nil, err
+		}
+		values.Set("channel", m.Room)
+	}
+
+	var messages []ScheduledMessage
+	for {
+		var resp struct {
+			slack.SlackResponse
+			Messages []struct {
+				ID          string `json:"id"`
+				Channel     string `json:"channel_id"`
+				Text        string `json:"text"`
+				PostAt      int64  `json:"post_at"`
+				DateCreated int64  `json:"date_created"`
+			} `json:"scheduled_messages"`
+			Metadata struct {
+				NextCursor string `json:"next_cursor"`
+			} `json:"response_metadata"`
+		}
+		if err := retry(a.warnf, func() error {
+			return webapi.Form(a.token, "chat.scheduledMessages.list", values, &resp)
+		}); err != nil {
+			return nil, err
+		}
+
+		for _, m := range resp.Messages {
+			messages = append(messages, ScheduledMessage{
+				ID:      m.ID,
+				Channel: m.Channel,
+				Text:    m.Text,
+				PostAt:  time.Unix(m.PostAt, 0),
+				Created: time.Unix(m.DateCreated, 0),
+			})
+		}
+
+		if resp.Metadata.NextCursor == "" {
+			return messages, nil
+		}
+		values.Set("cursor", resp.Metadata.NextCursor)
+	}
+}
+
+// Unschedule cancels a scheduled message before it's posted
+func (a *Adapter) Unschedule(room, id string) error {
+	m := bot.Message{Room: room}
+	if err := parseRoom(a, &m); err != nil {
+		return err
+	}
+	if m.Room == "" || id == "" {
+		return errors.New("No scheduled message to cancel")
+	}
+
+	values := url.Values{
+		"channel":              {m.Room},
+		"scheduled_message_id": {id},
+		"as_user":              {"true"},
+	}
+	return retry(a.warnf, func() error {
+		var resp slack.SlackResponse
+		return webapi.Form(a.token, "chat.deleteScheduledMessage", values, &resp)
+	})
+}
@@ -0,0 +1,95 @@
+package slack
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/botopolis/bot"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdapterSchedule(t *testing.T) {
+	var form map[string][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/chat.scheduleMessage", r.URL.Path)
+		r.ParseForm()
+		form = r.PostForm
+		w.Write([]byte(`{"ok":true,"channel":"C1234","scheduled_message_id":"Q1298393284","post_at":1562180400}`))
+	}))
+	defer server.Close()
+
+	defer useAPI(server.URL)()
+
+	store := newTestStore()
+	store.Channel.ID = "C1234"
+	store.Channel.Name = "standup"
+
+	a := New("xoxb-1")
+	a.Store = store
+	at := time.Unix(1562180400, 0)
+
+	id, err := a.Schedule(bot.Message{Room: "standup", Text: "Standup time!"}, at)
+	assert.NoError(t, err)
+	assert.Equal(t, "Q1298393284", id)
+	assert.Equal(t, "C1234", form["channel"][0])
+	assert.Equal(t, "Standup time!", form["text"][0])
+	assert.Equal(t, "1562180400", form["post_at"][0])
+
+	_, err = a.Schedule(bot.Message{Room: "C1234", Params: testBlocks}, at)
+	assert.NoError(t, err)
+	assert.Equal(t, testBlocks.fallback(), form["text"][0])
+	assert.NotEmpty(t, form["blocks"])
+
+	_, err = a.Schedule(bot.Message{Room: "random", Text: "Standup time!"}, at)
+	assert.Error(t, err)
+	_, err = a.Schedule(bot.Message{Room: "C1234"}, at)
+	assert.Error(t, err)
+}
+
+func TestAdapterScheduledMessages(t *testing.T) {
+	var cursors []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/chat.scheduledMessages.list", r.URL.Path)
+		r.ParseForm()
+		assert.Equal(t, "C1234", r.PostForm.Get("channel"))
+		cursors = append(cursors, r.PostForm.Get("cursor"))
+		if r.PostForm.Get("cursor") == "" {
+			w.Write([]byte(`{"ok":true,"scheduled_messages":[{"id":"Q1","channel_id":"C1234","post_at":1562180400,"date_created":1562177709,"text":"Standup time!"}],"response_metadata":{"next_cursor":"next"}}`))
+			return
+		}
+		w.Write([]byte(`{"ok":true,"scheduled_messages":[{"id":"Q2","channel_id":"C1234","post_at":1562266800,"date_created":1562177710,"text":"Retro time!"}],"response_metadata":{"next_cursor":""}}`))
+	}))
+	defer server.Close()
+
+	defer useAPI(server.URL)()
+
+	messages, err := New("xoxb-1").ScheduledMessages("C1234")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"", "next"}, cursors)
+	assert.Equal(t, []ScheduledMessage{
+		{ID: "Q1", Channel: "C1234", Text: "Standup time!", PostAt: time.Unix(1562180400, 0), Created: time.Unix(1562177709, 0)},
+		{ID: "Q2", Channel: "C1234", Text: "Retro time!", PostAt: time.Unix(1562266800, 0), Created: time.Unix(1562177710, 0)},
+	}, messages)
+}
+
+func TestAdapterUnschedule(t *testing.T) {
+	var form map[string][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/chat.deleteScheduledMessage", r.URL.Path)
+		r.ParseForm()
+		form = r.PostForm
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	defer useAPI(server.URL)()
+
+	a := New("xoxb-1")
+	assert.NoError(t, a.Unschedule("C1234", "Q1"))
+	assert.Equal(t, "C1234", form["channel"][0])
+	assert.Equal(t, "Q1", form["scheduled_message_id"][0])
+
+	assert.Error(t, a.Unschedule("C1234", ""))
+}
@@ -2,6 +2,7 @@ package slack
 
 import (
 	"errors"
+	"time"
 
 	"github.com/botopolis/bot"
 	"github.com/nlopes/slack"
@@ -14,16 +15,20 @@ func (l slackLogger) Output(i int, s string) error {
 	return nil
 }
 
+// transport is how the adapter talks to Slack: over RTM, Socket Mode or
+// the Events API.
+type transport interface {
+	Connect() chan bot.Message
+	Disconnect()
+	Send(bot.Message) (MessageRef, error)
+	React(bot.Message) error
+	SetTopic(room, topic string) error
+}
+
 // Adapter is the bot slack adapter it implements
 // bot.Plugin and bot.Chat interfaces
 type Adapter struct {
-	proxy interface {
-		Connect() chan bot.Message
-		Disconnect()
-		Send(bot.Message) error
-		React(bot.Message) error
-		SetTopic(room, topic string) error
-	}
+	proxy transport
 
 	Robot  *bot.Robot
 	Client *slack.Client
@@ -31,16 +36,55 @@ type Adapter struct {
 
 	BotID string
 	Name  string
+
+	// ChannelRate is the minimum time between messages sent to one channel
+	ChannelRate time.Duration
+	// GlobalRate is the minimum time between any two messages sent
+	GlobalRate time.Duration
+	// FlushTimeout is how long Unload waits for queued messages to be sent
+	FlushTimeout time.Duration
+	// Verification applies to Events API requests
+	Verification
+
+	token string
+	conn  connState
+}
+
+func newAdapter(token string) *Adapter {
+	client := slack.New(token)
+	return &Adapter{
+		Client:       client,
+		token:        token,
+		Store:        newMemoryStore(client),
+		ChannelRate:  time.Second,
+		GlobalRate:   100 * time.Millisecond,
+		FlushTimeout: 5 * time.Second,
+	}
 }
 
 // New called with one's slack token provides a new adapter
 func New(secret string) *Adapter {
-	client := slack.New(secret)
-	a := &Adapter{
-		Client: client,
-		Store:  newMemoryStore(client),
-	}
-	a.proxy = newProxy(a)
+	a := newAdapter(secret)
+	a.proxy = newQueue(a, newProxy(a))
+	return a
+}
+
+// NewSocketMode provides a new adapter which receives events over Slack's
+// Socket Mode instead of RTM. It takes an app-level token (xapp-) used to
+// open the socket, and a bot token (xoxb-) used for the web API.
+func NewSocketMode(appToken, botToken string) *Adapter {
+	a := newAdapter(botToken)
+	a.proxy = newQueue(a, newSocketProxy(a, appToken))
+	return a
+}
+
+// NewEventsAPI provides a new adapter which receives events from Slack's
+// Events API over HTTP. The webhook is mounted at path on the robot's
+// router and requests are verified with the app's signing secret.
+// Messages are sent using the web API with the bot token.
+func NewEventsAPI(path, signingSecret, botToken string) *Adapter {
+	a := newAdapter(botToken)
+	a.proxy = newQueue(a, newEventsProxy(a, path, signingSecret))
 	return a
 }
 
@@ -49,32 +93,67 @@ func (a *Adapter) Load(r *bot.Robot) {
 	slack.SetLogger(&slackLogger{r.Logger})
 	a.Client.SetDebug(true)
 	a.Robot = r
+
+	if s, ok := a.Store.(interface{ setLogger(bot.Logger) }); ok {
+		s.setLogger(r.Logger)
+	}
+
+	// Transports receiving events over HTTP need the robot's router
+	if m, ok := a.proxy.(interface{ mount(*bot.Robot) }); ok {
+		m.mount(r)
+	}
 }
 
-// Unload disconnects from slack's RTM socket
-func (a *Adapter) Unload(r *bot.Robot) { a.proxy.Disconnect() }
+// Unload disconnects from slack's RTM or Socket Mode socket, and saves
+// the store if it is persisted (see FileStore)
+func (a *Adapter) Unload(r *bot.Robot) {
+	a.proxy.Disconnect()
+	if s, ok := a.Store.(interface{ Save() error }); ok {
+		if err := s.Save(); err != nil {
+			r.Logger.Error("slack: Unable to save store.", err)
+		}
+	}
+}
+
+// warnf logs a warning once the robot is loaded
+func (a *Adapter) warnf(format string, v ...interface{}) {
+	if a.Robot != nil {
+		a.Robot.Logger.Warnf(format, v...)
+	}
+}
 
 // Username returns the bot's username
 func (a *Adapter) Username() string { return a.Name }
 
-// Messages connects to Slack's RTM API and channels messages through
+// Messages connects to Slack's RTM API (or Socket Mode) and channels messages through
 func (a *Adapter) Messages() <-chan bot.Message { return a.proxy.Connect() }
 
 func emptyMessage(m bot.Message) bool {
+	if _, ok := m.Params.(Thread); ok {
+		return m.Text == ""
+	}
 	return m.Text == "" && m.Params == nil
 }
 
 // Send send messages to Slack. If only text is provided, it uses
 // the already open RTM connection. If slack.PostMessageParamters
-// are provided in the message.Params field, it will send a web
-// API request.
+// or Blocks are provided in the message.Params field, it will send
+// a web API request.
 func (a *Adapter) Send(m bot.Message) error {
+	_, err := a.Post(m)
+	return err
+}
+
+// Post does the same thing as send, but returns a reference to the message
+// sent so it can be updated or deleted later on. Over RTM, it waits for
+// Slack to acknowledge the message.
+func (a *Adapter) Post(m bot.Message) (MessageRef, error) {
 	if emptyMessage(m) {
-		return nil
+		return MessageRef{}, nil
 	}
 
 	if err := a.parse(&m, parseRoom, parseParams); err != nil {
-		return err
+		return MessageRef{}, err
 	}
 
 	return a.proxy.Send(m)
@@ -97,7 +176,8 @@ func (a *Adapter) Direct(m bot.Message) error {
 		return err
 	}
 
-	return a.proxy.Send(m)
+	_, err := a.proxy.Send(m)
+	return err
 }
 
 // Reply does the same thing as send, but prefixes the message
@@ -125,7 +205,24 @@ func (a *Adapter) Reply(m bot.Message) error {
 		m.Text = "<@" + m.User + "> " + m.Text
 	}
 
-	return a.proxy.Send(m)
+	_, err := a.proxy.Send(m)
+	return err
+}
+
+// ReplyInThread does the same thing as reply, but posts the message in
+// the thread of the message being replied to (requires an Envelope to be
+// set), starting one if need be. To also show the reply in the channel,
+// set Broadcast on Thread or ReplyBroadcast on slack.PostMessageParameters.
+func (a *Adapter) ReplyInThread(m bot.Message) error {
+	if emptyMessage(m) {
+		return nil
+	}
+
+	if err := a.parse(&m, parseThread); err != nil {
+		return err
+	}
+
+	return a.Reply(m)
 }
 
 // Topic uses the web API to change the topic. It prefers
@@ -204,6 +204,69 @@ func TestReply(t *testing.T) {
 	}
 }
 
+func TestReplyInThread(t *testing.T) {
+	user := "U1234"
+	envelope := slack.Message{}
+	envelope.User = user
+	envelope.Timestamp = "1.1"
+
+	cases := []struct {
+		In  bot.Message
+		Out bot.Message
+		Err bool
+	}{
+		{
+			In: bot.Message{Room: "general", Text: "foo", Envelope: envelope},
+			Out: bot.Message{
+				User:     user,
+				Room:     "C1234",
+				Text:     "<@U1234> foo",
+				Envelope: envelope,
+				Params:   Thread{Timestamp: "1.1"},
+			},
+		},
+		{
+			In: bot.Message{Room: "general", Text: "foo", Envelope: envelope, Params: Thread{Broadcast: true}},
+			Out: bot.Message{
+				User:     user,
+				Room:     "C1234",
+				Text:     "<@U1234> foo",
+				Envelope: envelope,
+				Params:   Thread{Timestamp: "1.1", Broadcast: true},
+			},
+		},
+		{
+			In:  bot.Message{Room: "general", Text: "foo"},
+			Err: true,
+		},
+	}
+	store := newTestStore()
+	store.Channel.ID = "C1234"
+	store.Channel.Name = "general"
+	store.User.ID = user
+	store.User.Name = "Jean"
+
+	for _, c := range cases {
+		proxy, run := setUpProxySend(t, c.Out)
+		adapter := Adapter{Store: store, proxy: proxy, BotID: user}
+		err := adapter.ReplyInThread(c.In)
+		if c.Err {
+			assert.NotNil(t, err)
+			assert.False(t, *run)
+		} else {
+			assert.Nil(t, err)
+			assert.True(t, *run)
+		}
+	}
+}
+
+func TestThreadTimestamp(t *testing.T) {
+	threaded := slack.Message{Msg: slack.Msg{Timestamp: "2.2", ThreadTimestamp: "1.1"}}
+	assert.Equal(t, "1.1", ThreadTimestamp(bot.Message{Envelope: threaded}))
+	assert.Equal(t, "", ThreadTimestamp(bot.Message{Envelope: slack.Message{}}))
+	assert.Equal(t, "", ThreadTimestamp(bot.Message{}))
+}
+
 func TestTopic(t *testing.T) {
 	cases := []struct {
 		In  bot.Message
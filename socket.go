@@ -0,0 +1,213 @@
+package slack
+
+import (
+	"encoding/json"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/botopolis/bot"
+	"github.com/botopolis/slack/internal/webapi"
+	"github.com/gorilla/websocket"
+	"github.com/nlopes/slack"
+)
+
+// socketPing is how often we ping Slack over Socket Mode. A connection
+// we've heard nothing from, not even a pong, for two of these is dropped
+// and reopened. It's swapped out in tests.
+var socketPing = 30 * time.Second
+
+// socketEnvelope is the wrapper Slack sends every Socket Mode frame in
+type socketEnvelope struct {
+	Type       string          `json:"type"`
+	EnvelopeID string          `json:"envelope_id"`
+	Reason     string          `json:"reason"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+// socketProxy connects to Slack through Socket Mode. Incoming events are
+// turned into their RTM equivalents and handed to proxy.Forward.
+type socketProxy struct {
+	*webProxy
+	appToken string
+
+	mu   sync.Mutex
+	conn *websocket.Conn
+}
+
+func newSocketProxy(a *Adapter, appToken string) *socketProxy {
+	return &socketProxy{
+		webProxy: newWebProxy(a),
+		appToken: appToken,
+	}
+}
+
+func (p *socketProxy) Connect() chan bot.Message {
+	events := make(chan slack.RTMEvent, 32)
+	go p.ManageConnection(events)
+	ch := make(chan bot.Message, 32)
+	go p.Forward(events, ch)
+	return ch
+}
+
+func (p *socketProxy) Disconnect() {
+	p.halt()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.conn != nil {
+		p.conn.Close()
+	}
+}
+
+// ManageConnection keeps a Socket Mode connection open until Disconnect
+// is called, sending everything it receives to out. Failed connections
+// are retried with an exponential backoff.
+func (p *socketProxy) ManageConnection(out chan<- slack.RTMEvent) {
+	defer close(out)
+	for count := 1; ; count++ {
+		out <- slack.RTMEvent{
+			Type: "connecting",
+			Data: &slack.ConnectingEvent{Attempt: count, ConnectionCount: count},
+		}
+
+		err := p.run(out, count)
+		if p.stopped() {
+			out <- slack.RTMEvent{Type: "disconnected", Data: &slack.DisconnectedEvent{Intentional: true}}
+			return
+		}
+		if err == nil {
+			out <- slack.RTMEvent{Type: "disconnected", Data: &slack.DisconnectedEvent{}}
+			continue
+		}
+
+		if err.Error() == errInvalidAuth.Error() {
+			out <- slack.RTMEvent{Type: "invalid_auth", Data: &slack.InvalidAuthEvent{}}
+		} else {
+			out <- slack.RTMEvent{
+				Type: "connection_error",
+				Data: &slack.ConnectionErrorEvent{Attempt: count, ErrorObj: err},
+			}
+		}
+
+		select {
+		case <-p.stop:
+			out <- slack.RTMEvent{Type: "disconnected", Data: &slack.DisconnectedEvent{Intentional: true}}
+			return
+		case <-time.After(p.backoff.Duration()):
+		}
+	}
+}
+
+// run reads from a single websocket until it is closed. A nil error means
+// Slack asked us to reconnect.
+func (p *socketProxy) run(out chan<- slack.RTMEvent, count int) error {
+	wsURL, err := p.open()
+	if err != nil {
+		return err
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.conn = conn
+	p.mu.Unlock()
+	defer conn.Close()
+
+	// Disconnect may have come while we were dialing, in which case it
+	// couldn't close this connection
+	if p.stopped() {
+		return nil
+	}
+
+	// Without hearing from Slack, a half-open connection would look
+	// connected forever
+	interval := socketPing
+	alive := func() error { return conn.SetReadDeadline(time.Now().Add(2 * interval)) }
+	alive()
+	conn.SetPongHandler(func(string) error { return alive() })
+	conn.SetPingHandler(func(data string) error {
+		alive()
+		err := conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(interval))
+		if err == websocket.ErrCloseSent {
+			return nil
+		}
+		return err
+	})
+	done := make(chan struct{})
+	defer close(done)
+	go p.ping(conn, interval, done)
+
+	for {
+		var env socketEnvelope
+		if err := conn.ReadJSON(&env); err != nil {
+			return err
+		}
+		alive()
+
+		if env.EnvelopeID != "" {
+			ack := map[string]string{"envelope_id": env.EnvelopeID}
+			if err := conn.WriteJSON(ack); err != nil {
+				return err
+			}
+		}
+
+		switch env.Type {
+		case "hello":
+			ev, err := p.connected(count)
+			if err != nil {
+				return err
+			}
+			out <- ev
+		case "disconnect":
+			p.Robot.Logger.Debugf("slack: Socket Mode disconnect requested: %s", env.Reason)
+			return nil
+		case "events_api":
+			var cb struct {
+				Event json.RawMessage `json:"event"`
+			}
+			if err := json.Unmarshal(env.Payload, &cb); err != nil {
+				p.Robot.Logger.Errorf("slack: Invalid Socket Mode payload: %v", err)
+				continue
+			}
+			ev, err := rtmEvent(cb.Event)
+			if err != nil {
+				p.Robot.Logger.Debugf("slack: Skipping Socket Mode event: %v", err)
+				continue
+			}
+			out <- ev
+		default:
+			p.Robot.Logger.Warnf("slack: Skipping Socket Mode %s envelope, which isn't supported", env.Type)
+		}
+	}
+}
+
+// ping pings Slack every interval until done is closed
+func (p *socketProxy) ping(conn *websocket.Conn, interval time.Duration, done <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-t.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(interval)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// open asks Slack for a fresh websocket URL using the app-level token
+func (p *socketProxy) open() (string, error) {
+	var resp struct {
+		slack.SlackResponse
+		URL string `json:"url"`
+	}
+	if err := webapi.Form(p.appToken, "apps.connections.open", url.Values{}, &resp); err != nil {
+		return "", err
+	}
+
+	return resp.URL, nil
+}
@@ -0,0 +1,244 @@
+package slack
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/botopolis/bot"
+	"github.com/botopolis/bot/mock"
+	"github.com/gorilla/websocket"
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+const socketMessage = `{
+	"envelope_id": "E1",
+	"type": "events_api",
+	"payload": {
+		"type": "event_callback",
+		"event": {"type": "message", "channel": "C1234", "user": "U1234", "text": "hi"}
+	}
+}`
+
+// newSocketServer serves Socket Mode, saying hello on every connection
+// before handing it to handle
+func newSocketServer(t *testing.T, handle func(*websocket.Conn)) *httptest.Server {
+	return serveSocket(t, func() {}, handle)
+}
+
+// serveSocket is newSocketServer, calling opening before answering each
+// apps.connections.open
+func serveSocket(t *testing.T, opening func(), handle func(*websocket.Conn)) *httptest.Server {
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/apps.connections.open", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer xapp-1", r.Header.Get("Authorization"))
+		opening()
+		w.Write([]byte(`{"ok":true,"url":"ws` + strings.TrimPrefix(server.URL, "http") + `/ws"}`))
+	})
+	mux.HandleFunc("/auth.test", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ok":true,"user_id":"B1234","user":"beardroid"}`))
+	})
+	mux.HandleFunc("/users.info", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ok":false,"error":"user_not_found"}`))
+	})
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"hello"}`))
+		handle(conn)
+	})
+	server = httptest.NewServer(mux)
+	return server
+}
+
+func TestSocketProxy(t *testing.T) {
+	acks := make(chan string, 1)
+	server := newSocketServer(t, func(conn *websocket.Conn) {
+		conn.WriteMessage(websocket.TextMessage, []byte(socketMessage))
+
+		var ack map[string]string
+		if err := conn.ReadJSON(&ack); err == nil {
+			acks <- ack["envelope_id"]
+		}
+		conn.ReadMessage()
+	})
+	defer server.Close()
+
+	defer useAPI(server.URL)()
+
+	store := newTestStore()
+	store.User = slack.User{ID: "U1234", Name: "jean"}
+	store.Channel.ID = "C1234"
+	store.Channel.Name = "general"
+
+	a := NewSocketMode("xapp-1", "xoxb-1")
+	a.Store = store
+	a.Robot = &bot.Robot{Logger: mock.NewLogger()}
+
+	ch := a.Messages()
+	m := <-ch
+	assert.Equal(t, "E1", <-acks)
+	assert.Equal(t, "B1234", a.BotID)
+	assert.Equal(t, "beardroid", a.Username())
+	assert.Equal(t, "jean", m.User)
+	assert.Equal(t, "general", m.Room)
+	assert.Equal(t, "hi", m.Text)
+
+	a.Unload(a.Robot)
+	_, ok := <-ch
+	assert.False(t, ok)
+}
+
+func TestSocketProxy_unhandled(t *testing.T) {
+	acks := make(chan string, 1)
+	server := newSocketServer(t, func(conn *websocket.Conn) {
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"envelope_id":"E2","type":"interactive","payload":{}}`))
+
+		var ack map[string]string
+		if err := conn.ReadJSON(&ack); err == nil {
+			acks <- ack["envelope_id"]
+		}
+		conn.ReadMessage()
+	})
+	defer server.Close()
+
+	defer useAPI(server.URL)()
+
+	warnings := make(chan string, 1)
+	logger := mock.NewLogger()
+	logger.WritefFunc = func(l mock.Level, format string, v ...interface{}) {
+		if l == mock.WarnLevel {
+			select {
+			case warnings <- fmt.Sprintf(format, v...):
+			default:
+			}
+		}
+	}
+	a := NewSocketMode("xapp-1", "xoxb-1")
+	a.Store = newTestStore()
+	a.Robot = &bot.Robot{Logger: logger}
+
+	ch := a.Messages()
+	assert.Equal(t, "E2", <-acks)
+	assert.Contains(t, <-warnings, "interactive")
+
+	a.Unload(a.Robot)
+	for range ch {
+	}
+}
+
+func TestSocketProxy_disconnectOpening(t *testing.T) {
+	opening := make(chan struct{})
+	release := make(chan struct{})
+	closed := make(chan struct{})
+	server := serveSocket(t, func() {
+		opening <- struct{}{}
+		<-release
+	}, func(conn *websocket.Conn) {
+		// Returns once we close the connection
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				close(closed)
+				return
+			}
+		}
+	})
+	defer server.Close()
+
+	defer useAPI(server.URL)()
+
+	a := NewSocketMode("xapp-1", "xoxb-1")
+	a.Store = newTestStore()
+	a.Robot = &bot.Robot{Logger: mock.NewLogger()}
+
+	ch := a.Messages()
+	<-opening
+	a.Unload(a.Robot)
+	close(release)
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("connection opened during Disconnect was left open")
+	}
+	for range ch {
+	}
+}
+
+func TestSocketProxy_halfOpen(t *testing.T) {
+	defer func(d time.Duration) { socketPing = d }(socketPing)
+	socketPing = 20 * time.Millisecond
+
+	// The server stops reading, so our pings go unanswered
+	gone := make(chan struct{})
+	server := newSocketServer(t, func(*websocket.Conn) { <-gone })
+	defer server.Close()
+	defer close(gone)
+
+	defer useAPI(server.URL)()
+
+	a := NewSocketMode("xapp-1", "xoxb-1")
+	a.Store = newTestStore()
+	a.Robot = &bot.Robot{Logger: mock.NewLogger()}
+
+	dropped := make(chan StateChange, 1)
+	a.OnStateChange(func(c StateChange) {
+		if c.From == Connected {
+			select {
+			case dropped <- c:
+			default:
+			}
+		}
+	})
+
+	ch := a.Messages()
+	c := <-dropped
+	assert.Equal(t, Reconnecting, c.To)
+	assert.Error(t, c.Err)
+
+	a.Unload(a.Robot)
+	for range ch {
+	}
+}
+
+func TestSocketProxy_invalidAuth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ok":false,"error":"invalid_auth"}`))
+	}))
+	defer server.Close()
+
+	defer useAPI(server.URL)()
+
+	a := NewSocketMode("xapp-1", "xoxb-1")
+	a.Robot = &bot.Robot{Logger: mock.NewLogger()}
+
+	rejected := make(chan StateChange, 1)
+	a.OnStateChange(func(c StateChange) {
+		if c.Err != nil {
+			select {
+			case rejected <- c:
+			default:
+			}
+		}
+	})
+
+	ch := a.Messages()
+	c := <-rejected
+	assert.Equal(t, Disconnected, c.To)
+	assert.Equal(t, errInvalidAuth, c.Err)
+
+	// We keep retrying until unloaded
+	a.Unload(a.Robot)
+	_, ok := <-ch
+	assert.False(t, ok)
+}
@@ -0,0 +1,98 @@
+package slack
+
+import (
+	"sync"
+	"time"
+)
+
+// State describes the adapter's connection to Slack
+type State int
+
+const (
+	// Disconnected means we aren't connected and won't be until the
+	// next retry (or ever, if the adapter was unloaded)
+	Disconnected State = iota
+	// Connecting means we're connecting for the first time
+	Connecting
+	// Connected means events are flowing
+	Connected
+	// Reconnecting means the connection dropped and we're trying again
+	Reconnecting
+)
+
+func (s State) String() string {
+	switch s {
+	case Connecting:
+		return "connecting"
+	case Connected:
+		return "connected"
+	case Reconnecting:
+		return "reconnecting"
+	default:
+		return "disconnected"
+	}
+}
+
+// StateChange is given to OnStateChange callbacks
+type StateChange struct {
+	From State
+	To   State
+	// At is when the change happened
+	At time.Time
+	// Err is the reason for the change, if it was caused by an error
+	Err error
+}
+
+type connState struct {
+	mu        sync.Mutex
+	state     State
+	since     time.Time
+	connected bool
+	hooks     []func(StateChange)
+}
+
+// State returns the state of the connection to Slack and since when
+// it's been that way. This can be used to alert when the robot has been
+// disconnected for too long.
+func (a *Adapter) State() (State, time.Time) {
+	a.conn.mu.Lock()
+	defer a.conn.mu.Unlock()
+	return a.conn.state, a.conn.since
+}
+
+// OnStateChange registers fn to be called each time the connection state
+// changes, e.g. so plugins can pause work while Slack is unreachable.
+// Callbacks are run in order on the goroutine reading from Slack, so they
+// shouldn't block.
+func (a *Adapter) OnStateChange(fn func(StateChange)) {
+	a.conn.mu.Lock()
+	defer a.conn.mu.Unlock()
+	a.conn.hooks = append(a.conn.hooks, fn)
+}
+
+// setState moves to a new state, notifying callbacks if it changed.
+// Once connected, any attempt to connect again counts as reconnecting.
+func (a *Adapter) setState(to State, err error) {
+	a.conn.mu.Lock()
+	if to == Connecting && a.conn.connected {
+		to = Reconnecting
+	}
+	if to == Connected {
+		a.conn.connected = true
+	}
+	from := a.conn.state
+	if from == to {
+		a.conn.mu.Unlock()
+		return
+	}
+
+	change := StateChange{From: from, To: to, At: time.Now(), Err: err}
+	a.conn.state = to
+	a.conn.since = change.At
+	hooks := a.conn.hooks
+	a.conn.mu.Unlock()
+
+	for _, fn := range hooks {
+		fn(change)
+	}
+}
@@ -0,0 +1,45 @@
+package slack
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdapterSetState(t *testing.T) {
+	a := New("")
+	var changes []StateChange
+	a.OnStateChange(func(c StateChange) { changes = append(changes, c) })
+
+	a.setState(Connecting, nil)
+	a.setState(Connecting, nil)
+	a.setState(Connected, nil)
+	err := errors.New("boom")
+	a.setState(Connecting, err)
+	a.setState(Disconnected, nil)
+
+	assert.Len(t, changes, 4)
+	assert.Equal(t, StateChange{From: Disconnected, To: Connecting, At: changes[0].At}, changes[0])
+	assert.Equal(t, Connected, changes[1].To)
+	assert.Equal(t, Reconnecting, changes[2].To)
+	assert.Equal(t, err, changes[2].Err)
+	assert.Equal(t, Disconnected, changes[3].To)
+
+	state, since := a.State()
+	assert.Equal(t, Disconnected, state)
+	assert.Equal(t, changes[3].At, since)
+}
+
+func TestBackoff(t *testing.T) {
+	b := backoff{Min: time.Second, Max: 10 * time.Second}
+	for _, ceil := range []time.Duration{1, 2, 4, 8, 10, 10} {
+		d := b.Duration()
+		assert.True(t, d >= time.Second, d.String())
+		assert.True(t, d <= ceil*time.Second, d.String())
+	}
+
+	b.Reset()
+	assert.Equal(t, time.Second, b.Duration())
+}
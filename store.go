@@ -1,16 +1,19 @@
 package slack
 
 import (
+	"context"
+	"encoding/json"
 	"sync"
 
+	"github.com/botopolis/bot"
 	"github.com/nlopes/slack"
 )
 
 // Store is the interface to expect from adapter.Store
 type Store interface {
-	// Load takes slack info and adds new users and channels from it
+	// Load takes slack info and adds new users, channels and groups from it
 	Load(*slack.Info)
-	// Update queries Slack's web API for users and channels
+	// Update queries Slack's web API for users and conversations
 	Update() error
 	// UserByID queries the store for a User by ID
 	UserByID(id string) (slack.User, bool)
@@ -18,7 +21,10 @@ type Store interface {
 	UserByName(name string) (slack.User, bool)
 	// UserByEmail queries the store for a User by Name
 	UserByEmail(name string) (slack.User, bool)
-	// ChannelByID queries the store for a Channel by ID
+	// ChannelByID queries the store for a Channel by ID. Channels include
+	// private channels, group DMs and shared channels as well as public ones.
+	// Their Members are only filled in when Slack listed them, and aren't
+	// kept up to date as people join and leave.
 	ChannelByID(id string) (slack.Channel, bool)
 	// ChannelByName queries the store for a Channel by Name
 	ChannelByName(id string) (slack.Channel, bool)
@@ -26,20 +32,35 @@ type Store interface {
 	IMByID(id string) (slack.IM, bool)
 	// IMByUserID queries the store for a DM by User ID
 	IMByUserID(userID string) (slack.IM, bool)
+	// SetUser adds or replaces a User
+	SetUser(slack.User)
+	// SetChannel adds or replaces a Channel
+	SetChannel(slack.Channel)
+	// RemoveChannel removes a Channel by ID
+	RemoveChannel(id string)
+	// SetIM adds or replaces an IM
+	SetIM(slack.IM)
 }
 
 type memoryStore struct {
 	mu       sync.RWMutex
 	client   *slack.Client
+	logf     func(string, ...interface{})
 	indices  map[string]string
 	users    map[string]slack.User
 	channels map[string]slack.Channel
 	ims      map[string]slack.IM
+
+	// changes made while Updates are running, to make again on the data
+	// they fetched
+	updating int
+	changes  []func(*memoryStore)
 }
 
 func newMemoryStore(c *slack.Client) *memoryStore {
 	m := &memoryStore{
 		client:   c,
+		logf:     func(string, ...interface{}) {},
 		indices:  make(map[string]string),
 		users:    make(map[string]slack.User),
 		channels: make(map[string]slack.Channel),
@@ -52,33 +73,136 @@ func (s *memoryStore) Load(i *slack.Info) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	for _, u := range i.Users {
-		s.users[u.ID] = u
-		s.indices["user:name:"+u.Name] = u.ID
-		s.indices["user:email:"+u.Profile.Email] = u.ID
+		s.setUser(u)
 	}
 
 	for _, ch := range i.Channels {
-		s.channels[ch.ID] = ch
-		s.indices["channel:name:"+ch.Name] = ch.ID
+		s.setChannel(ch)
+	}
+
+	for _, g := range i.Groups {
+		s.setChannel(groupChannel(g))
 	}
 
 	for _, im := range i.IMs {
-		s.ims[im.ID] = im
-		s.indices["im:userID:"+im.User] = im.ID
+		s.setIM(im)
 	}
 }
 
-func (s *memoryStore) Update() (err error) {
-	info := slack.Info{}
-	if info.Users, err = s.client.GetUsers(); err != nil {
+// Update fetches every user and conversation, page by page, waiting out
+// rate limits as it goes. The store only switches to the new data once
+// everything has been fetched, so lookups never see a partial list.
+// Changes made while it runs are kept.
+func (s *memoryStore) Update() error {
+	s.mu.Lock()
+	s.updating++
+	start := len(s.changes)
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		if s.updating--; s.updating == 0 {
+			s.changes = nil
+		}
+		s.mu.Unlock()
+	}()
+
+	users, err := s.fetchUsers()
+	if err != nil {
 		return err
 	}
 
-	if info.Channels, err = s.client.GetChannels(true); err != nil {
+	channels, err := s.fetchConversations(conversationTypes)
+	if err != nil && err.Error() == "missing_scope" {
+		// Without groups:read and mpim:read we can still see public channels
+		channels, err = s.fetchConversations([]string{"public_channel"})
+	}
+	if err != nil {
 		return err
 	}
-	s.Load(&info)
-	return err
+
+	next := newMemoryStore(s.client)
+	next.Load(&slack.Info{Users: users, Channels: channels})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// IMs aren't listed by Update, so keep the ones we know about
+	for _, im := range s.ims {
+		next.setIM(im)
+	}
+	for _, change := range s.changes[start:] {
+		change(next)
+	}
+	s.indices, s.users, s.channels, s.ims = next.indices, next.users, next.channels, next.ims
+	s.logf("slack: Store updated with %d users and %d channels", len(users), len(channels))
+
+	return nil
+}
+
+// conversationTypes are the kinds of conversation indexed as channels.
+// Shared channels are listed as public or private channels.
+var conversationTypes = []string{"public_channel", "private_channel", "mpim"}
+
+func (s *memoryStore) fetchUsers() ([]slack.User, error) {
+	var all []slack.User
+	p := s.client.GetUsersPaginated(slack.GetUsersOptionLimit(200))
+	for {
+		var next slack.UserPagination
+		err := retry(s.logf, func() (err error) {
+			next, err = p.Next(context.Background())
+			return err
+		})
+		if p.Done(err) {
+			return all, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		p = next
+		all = append(all, p.Users...)
+		s.logf("slack: Fetched %d users", len(all))
+	}
+}
+
+func (s *memoryStore) fetchConversations(types []string) ([]slack.Channel, error) {
+	var all []slack.Channel
+	params := &slack.GetConversationsParameters{
+		// Archived channels are kept so that messages and topics in them
+		// can still be looked up by name
+		ExcludeArchived: "false",
+		Limit:           1000,
+		Types:           types,
+	}
+	for {
+		var (
+			channels []slack.Channel
+			cursor   string
+		)
+		err := retry(s.logf, func() (err error) {
+			channels, cursor, err = s.client.GetConversations(params)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, channels...)
+		s.logf("slack: Fetched %d channels", len(all))
+		if cursor == "" {
+			return all, nil
+		}
+		params.Cursor = cursor
+	}
+}
+
+// groupChannel converts a private channel or group DM from RTM's
+// connection info into the Channel the conversations API would return.
+func groupChannel(g slack.Group) slack.Channel {
+	var ch slack.Channel
+	b, _ := json.Marshal(g)
+	json.Unmarshal(b, &ch)
+	return ch
 }
 
 func (s *memoryStore) UserByID(id string) (slack.User, bool) {
@@ -89,15 +213,11 @@ func (s *memoryStore) UserByID(id string) (slack.User, bool) {
 }
 
 func (s *memoryStore) UserByName(name string) (slack.User, bool) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	return s.UserByID(s.indices["user:name:"+name])
+	return s.UserByID(s.index("user:name:" + name))
 }
 
 func (s *memoryStore) UserByEmail(name string) (slack.User, bool) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	return s.UserByID(s.indices["user:email:"+name])
+	return s.UserByID(s.index("user:email:" + name))
 }
 
 func (s *memoryStore) ChannelByID(id string) (slack.Channel, bool) {
@@ -108,9 +228,7 @@ func (s *memoryStore) ChannelByID(id string) (slack.Channel, bool) {
 }
 
 func (s *memoryStore) ChannelByName(name string) (slack.Channel, bool) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	return s.ChannelByID(s.indices["channel:name:"+name])
+	return s.ChannelByID(s.index("channel:name:" + name))
 }
 
 func (s *memoryStore) IMByID(id string) (slack.IM, bool) {
@@ -121,7 +239,99 @@ func (s *memoryStore) IMByID(id string) (slack.IM, bool) {
 }
 
 func (s *memoryStore) IMByUserID(userID string) (slack.IM, bool) {
+	return s.IMByID(s.index("im:userID:" + userID))
+}
+
+// setLogger reports progress of updates to the robot's logger
+func (s *memoryStore) setLogger(l bot.Logger) { s.logf = l.Infof }
+
+// index looks up an ID without holding the lock once it returns, so that
+// lookups by name don't take the read lock twice.
+func (s *memoryStore) index(key string) string {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return s.IMByID(s.indices["im:userID:"+userID])
+	return s.indices[key]
+}
+
+// info returns a copy of everything in the store
+func (s *memoryStore) info() *slack.Info {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	i := &slack.Info{}
+	for _, u := range s.users {
+		i.Users = append(i.Users, u)
+	}
+	for _, ch := range s.channels {
+		i.Channels = append(i.Channels, ch)
+	}
+	for _, im := range s.ims {
+		i.IMs = append(i.IMs, im)
+	}
+	return i
+}
+
+func (s *memoryStore) SetUser(u slack.User) {
+	s.apply(func(s *memoryStore) { s.setUser(u) })
+}
+
+func (s *memoryStore) SetChannel(ch slack.Channel) {
+	s.apply(func(s *memoryStore) { s.setChannel(ch) })
+}
+
+func (s *memoryStore) RemoveChannel(id string) {
+	s.apply(func(s *memoryStore) { s.removeChannel(id) })
+}
+
+func (s *memoryStore) SetIM(im slack.IM) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.setIM(im)
+}
+
+// apply makes a change, remembering it while an Update is running so it
+// isn't lost when the fetched data is swapped in
+func (s *memoryStore) apply(change func(*memoryStore)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	change(s)
+	if s.updating > 0 {
+		s.changes = append(s.changes, change)
+	}
+}
+
+// setUser, setChannel, removeChannel and setIM expect the caller to hold
+// the write lock. They drop any index the previous version of the record
+// had.
+
+func (s *memoryStore) setUser(u slack.User) {
+	if old, ok := s.users[u.ID]; ok {
+		delete(s.indices, "user:name:"+old.Name)
+		delete(s.indices, "user:email:"+old.Profile.Email)
+	}
+	s.users[u.ID] = u
+	s.indices["user:name:"+u.Name] = u.ID
+	s.indices["user:email:"+u.Profile.Email] = u.ID
+}
+
+func (s *memoryStore) setChannel(ch slack.Channel) {
+	if old, ok := s.channels[ch.ID]; ok {
+		delete(s.indices, "channel:name:"+old.Name)
+	}
+	s.channels[ch.ID] = ch
+	s.indices["channel:name:"+ch.Name] = ch.ID
+}
+
+func (s *memoryStore) removeChannel(id string) {
+	if old, ok := s.channels[id]; ok {
+		delete(s.indices, "channel:name:"+old.Name)
+	}
+	delete(s.channels, id)
+}
+
+func (s *memoryStore) setIM(im slack.IM) {
+	if old, ok := s.ims[im.ID]; ok {
+		delete(s.indices, "im:userID:"+old.User)
+	}
+	s.ims[im.ID] = im
+	s.indices["im:userID:"+im.User] = im.ID
 }
@@ -0,0 +1,193 @@
+package slack
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/nlopes/slack"
+)
+
+// fileStoreDelay is how long FileStore waits after a change before
+// writing its snapshot, so bursts of events only cause one write.
+const fileStoreDelay = 5 * time.Second
+
+// fileStoreRefresh is how long FileStore waits after a refresh before
+// another one, so reconnecting often doesn't mean refetching everything
+// every time.
+const fileStoreRefresh = 15 * time.Minute
+
+// FileStore is a Store which keeps a JSON snapshot of its users, channels
+// and IMs on disk. Lookups are served from the snapshot straight away on
+// startup, while Update refreshes from Slack's web API in the background.
+//
+// To use it, assign it to Adapter.Store before the robot runs.
+type FileStore struct {
+	*memoryStore
+	// OnError is called with errors from background refreshes and saves
+	OnError func(error)
+
+	path       string
+	lock       sync.Mutex
+	loaded     bool
+	pending    *time.Timer
+	refreshing bool
+	refreshed  time.Time
+
+	// saving is held from taking a snapshot until it's on disk, so an
+	// older one can't overwrite a newer one
+	saving sync.Mutex
+}
+
+// NewFileStore creates a FileStore persisting to path, loading whatever
+// state was last saved there. A missing file is not an error.
+func NewFileStore(path string, client *slack.Client) (*FileStore, error) {
+	s := &FileStore{
+		memoryStore: newMemoryStore(client),
+		path:        path,
+		OnError:     func(error) {},
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var info slack.Info
+	if err := json.Unmarshal(b, &info); err != nil {
+		return nil, err
+	}
+	s.memoryStore.Load(&info)
+	s.loaded = true
+
+	return s, nil
+}
+
+// Load adds users and channels from slack info and saves them
+func (s *FileStore) Load(i *slack.Info) {
+	s.memoryStore.Load(i)
+	s.schedule()
+}
+
+// Update queries Slack's web API for users and channels. If a snapshot
+// was loaded on startup it does so in the background and returns
+// immediately, otherwise it blocks until the store is filled. Only one
+// refresh runs at a time, and none within 15 minutes of the last one.
+func (s *FileStore) Update() error {
+	s.lock.Lock()
+	if s.refreshing || time.Since(s.refreshed) < fileStoreRefresh {
+		s.lock.Unlock()
+		return nil
+	}
+	s.refreshing = true
+	loaded := s.loaded
+	s.lock.Unlock()
+
+	if !loaded {
+		return s.refresh()
+	}
+
+	go func() {
+		if err := s.refresh(); err != nil {
+			s.OnError(err)
+		}
+	}()
+	return nil
+}
+
+func (s *FileStore) refresh() error {
+	err := s.memoryStore.Update()
+
+	s.lock.Lock()
+	s.refreshing = false
+	if err == nil {
+		s.loaded = true
+		s.refreshed = time.Now()
+	}
+	s.lock.Unlock()
+
+	if err != nil {
+		return err
+	}
+	return s.Save()
+}
+
+// SetUser adds or replaces a User and saves it
+func (s *FileStore) SetUser(u slack.User) {
+	s.memoryStore.SetUser(u)
+	s.schedule()
+}
+
+// SetChannel adds or replaces a Channel and saves it
+func (s *FileStore) SetChannel(ch slack.Channel) {
+	s.memoryStore.SetChannel(ch)
+	s.schedule()
+}
+
+// RemoveChannel removes a Channel by ID and saves the change
+func (s *FileStore) RemoveChannel(id string) {
+	s.memoryStore.RemoveChannel(id)
+	s.schedule()
+}
+
+// SetIM adds or replaces an IM and saves it
+func (s *FileStore) SetIM(im slack.IM) {
+	s.memoryStore.SetIM(im)
+	s.schedule()
+}
+
+// Save writes the snapshot to disk now. The file is replaced atomically
+// so a crash mid-write never leaves a corrupt snapshot behind.
+func (s *FileStore) Save() error {
+	s.lock.Lock()
+	if s.pending != nil {
+		s.pending.Stop()
+		s.pending = nil
+	}
+	s.lock.Unlock()
+
+	s.saving.Lock()
+	defer s.saving.Unlock()
+
+	b, err := json.Marshal(s.memoryStore.info())
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// schedule saves the snapshot once things have settled down
+func (s *FileStore) schedule() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.pending != nil {
+		return
+	}
+
+	s.pending = time.AfterFunc(fileStoreDelay, func() {
+		if err := s.Save(); err != nil {
+			s.OnError(err)
+		}
+	})
+}
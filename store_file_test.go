@@ -0,0 +1,124 @@
+package slack
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileStore_snapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "slack")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "store.json")
+
+	store, err := NewFileStore(path, &slack.Client{})
+	assert.NoError(t, err)
+	_, ok := store.UserByID("U1234")
+	assert.False(t, ok)
+
+	store.Load(slackUserInfo())
+	assert.NoError(t, store.Save())
+
+	store, err = NewFileStore(path, &slack.Client{})
+	assert.NoError(t, err)
+
+	user, ok := store.UserByName("Jean")
+	assert.True(t, ok)
+	assert.Equal(t, "U1234", user.ID)
+	channel, ok := store.ChannelByName("general")
+	assert.True(t, ok)
+	assert.Equal(t, "C1234", channel.ID)
+	im, ok := store.IMByUserID("U1234")
+	assert.True(t, ok)
+	assert.Equal(t, "D1234", im.ID)
+}
+
+func TestFileStore_corrupt(t *testing.T) {
+	f, err := ioutil.TempFile("", "slack")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString("{")
+	f.Close()
+
+	_, err = NewFileStore(f.Name(), &slack.Client{})
+	assert.Error(t, err)
+}
+
+func TestFileStore_update(t *testing.T) {
+	release := make(chan bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		switch r.URL.Path {
+		case "/users.list":
+			w.Write([]byte(`{"ok":true,"members":[{"id":"U4321","name":"Joan"}]}`))
+		case "/conversations.list":
+			w.Write([]byte(`{"ok":true,"channels":[]}`))
+		}
+	}))
+	defer server.Close()
+
+	defer useAPI(server.URL)()
+
+	dir, err := ioutil.TempDir("", "slack")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "store.json")
+
+	store, _ := NewFileStore(path, slack.New("xoxb-1"))
+	store.Load(slackUserInfo())
+	assert.NoError(t, store.Save())
+	store, _ = NewFileStore(path, slack.New("xoxb-1"))
+
+	// Update returns before Slack has answered
+	assert.NoError(t, store.Update())
+	_, ok := store.UserByName("Jean")
+	assert.True(t, ok)
+	_, ok = store.UserByName("Joan")
+	assert.False(t, ok)
+
+	close(release)
+	for i := 0; i < 100 && !ok; i++ {
+		time.Sleep(10 * time.Millisecond)
+		_, ok = store.UserByName("Joan")
+	}
+	assert.True(t, ok)
+}
+
+func TestFileStore_updateThrottled(t *testing.T) {
+	var lists int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users.list":
+			lists++
+			w.Write([]byte(`{"ok":true,"members":[{"id":"U4321","name":"Joan"}]}`))
+		case "/conversations.list":
+			w.Write([]byte(`{"ok":true,"channels":[]}`))
+		}
+	}))
+	defer server.Close()
+
+	defer useAPI(server.URL)()
+
+	dir, err := ioutil.TempDir("", "slack")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	store, _ := NewFileStore(filepath.Join(dir, "store.json"), slack.New("xoxb-1"))
+	assert.NoError(t, store.Update())
+	assert.Equal(t, 1, lists)
+
+	// Reconnecting straight away doesn't refresh again
+	assert.NoError(t, store.Update())
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	assert.False(t, store.refreshing)
+	assert.Equal(t, 1, lists)
+}
@@ -1,7 +1,10 @@
 package slack
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/nlopes/slack"
 	"github.com/stretchr/testify/assert"
@@ -82,6 +85,113 @@ func TestStore_ims(t *testing.T) {
 	assert.False(t, ok)
 }
 
+func TestStore_groups(t *testing.T) {
+	store := newMemoryStore(&slack.Client{})
+	group := slack.Group{IsGroup: true}
+	group.ID = "G1234"
+	group.Name = "secret"
+	group.IsMpIM = true
+	store.Load(&slack.Info{Groups: []slack.Group{group}})
+
+	channel, ok := store.ChannelByName("secret")
+	assert.True(t, ok)
+	assert.Equal(t, "G1234", channel.ID)
+	assert.True(t, channel.IsMpIM)
+}
+
+func TestStore_update(t *testing.T) {
+	var (
+		types    []string
+		archived []string
+		limited  int
+		waited   []time.Duration
+	)
+	defer stubPause(func(d time.Duration) { waited = append(waited, d) })()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users.list":
+			limited++
+			switch {
+			case limited == 1:
+				w.Header().Set("Retry-After", "3")
+				w.WriteHeader(http.StatusTooManyRequests)
+			case r.FormValue("cursor") == "":
+				w.Write([]byte(`{"ok":true,"members":[{"id":"U1234","name":"Jean"}],"response_metadata":{"next_cursor":"abc"}}`))
+			default:
+				w.Write([]byte(`{"ok":true,"members":[{"id":"U4321","name":"Joan"}]}`))
+			}
+		case "/conversations.list":
+			types = append(types, r.FormValue("types"))
+			archived = append(archived, r.FormValue("exclude_archived"))
+			switch {
+			case r.FormValue("types") != "public_channel":
+				w.Write([]byte(`{"ok":false,"error":"missing_scope"}`))
+			case r.FormValue("cursor") == "":
+				w.Write([]byte(`{"ok":true,"channels":[{"id":"C1234","name":"general"}],"response_metadata":{"next_cursor":"abc"}}`))
+			default:
+				w.Write([]byte(`{"ok":true,"channels":[{"id":"C4321","name":"random"}]}`))
+			}
+		}
+	}))
+	defer server.Close()
+
+	defer useAPI(server.URL)()
+
+	store := newMemoryStore(slack.New("xoxb-1"))
+	store.Load(slackUserInfo())
+	assert.NoError(t, store.Update())
+	assert.Equal(t, []time.Duration{3 * time.Second}, waited)
+	assert.Equal(t, []string{
+		"public_channel,private_channel,mpim",
+		"public_channel",
+		"public_channel",
+	}, types)
+	assert.Equal(t, []string{"false", "false", "false"}, archived)
+
+	_, ok := store.UserByName("Jean")
+	assert.True(t, ok)
+	_, ok = store.UserByName("Joan")
+	assert.True(t, ok)
+	_, ok = store.IMByUserID("U1234")
+	assert.True(t, ok)
+	_, ok = store.ChannelByName("general")
+	assert.True(t, ok)
+	_, ok = store.ChannelByName("random")
+	assert.True(t, ok)
+}
+
+func TestStore_updateKeepsChanges(t *testing.T) {
+	var store *memoryStore
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users.list":
+			w.Write([]byte(`{"ok":true,"members":[{"id":"U1234","name":"Jean"}]}`))
+		case "/conversations.list":
+			// Events arrive while the refresh is running
+			store.SetUser(slack.User{ID: "U4321", Name: "Joan"})
+			store.RemoveChannel("C4321")
+			w.Write([]byte(`{"ok":true,"channels":[{"id":"C1234","name":"general"},{"id":"C4321","name":"random"}]}`))
+		}
+	}))
+	defer server.Close()
+
+	defer useAPI(server.URL)()
+
+	store = newMemoryStore(slack.New("xoxb-1"))
+	assert.NoError(t, store.Update())
+
+	_, ok := store.UserByName("Jean")
+	assert.True(t, ok)
+	_, ok = store.UserByName("Joan")
+	assert.True(t, ok)
+	_, ok = store.ChannelByName("general")
+	assert.True(t, ok)
+	_, ok = store.ChannelByName("random")
+	assert.False(t, ok)
+	assert.Nil(t, store.changes)
+}
+
 func slackUserInfo() *slack.Info {
 	user := slack.User{ID: "U1234", Name: "Jean"}
 	channel := slack.Channel{}
@@ -96,3 +206,39 @@ func slackUserInfo() *slack.Info {
 		IMs:      []slack.IM{im},
 	}
 }
+
+func TestStore_set(t *testing.T) {
+	store := newMemoryStore(&slack.Client{})
+	store.Load(slackUserInfo())
+
+	user := slack.User{ID: "U1234", Name: "Joan"}
+	store.SetUser(user)
+	_, ok := store.UserByName("Jean")
+	assert.False(t, ok)
+	u, ok := store.UserByName("Joan")
+	assert.True(t, ok)
+	assert.Equal(t, user, u)
+
+	channel := slack.Channel{}
+	channel.ID = "C1234"
+	channel.Name = "random"
+	store.SetChannel(channel)
+	_, ok = store.ChannelByName("general")
+	assert.False(t, ok)
+	ch, ok := store.ChannelByName("random")
+	assert.True(t, ok)
+	assert.Equal(t, channel, ch)
+
+	store.RemoveChannel("C1234")
+	_, ok = store.ChannelByID("C1234")
+	assert.False(t, ok)
+	_, ok = store.ChannelByName("random")
+	assert.False(t, ok)
+
+	im := slack.IM{User: "U4321"}
+	im.ID = "D4321"
+	store.SetIM(im)
+	dm, ok := store.IMByUserID("U4321")
+	assert.True(t, ok)
+	assert.Equal(t, im, dm)
+}
@@ -0,0 +1,25 @@
+package slack
+
+import (
+	"github.com/botopolis/bot"
+	"github.com/nlopes/slack"
+)
+
+// Thread can be given as bot.Message.Params to send a plain text message
+// into a thread. For richer messages, slack.PostMessageParameters has
+// ThreadTimestamp and ReplyBroadcast fields which do the same thing.
+type Thread struct {
+	// Timestamp of the message which started the thread
+	Timestamp string
+	// Broadcast also shows the reply in the channel
+	Broadcast bool
+}
+
+// ThreadTimestamp returns the timestamp of the thread an incoming message
+// was posted in, or an empty string if it wasn't posted in a thread.
+func ThreadTimestamp(m bot.Message) string {
+	if msg, ok := m.Envelope.(slack.Message); ok {
+		return msg.ThreadTimestamp
+	}
+	return ""
+}
@@ -0,0 +1,78 @@
+package slack
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+
+	"github.com/botopolis/bot"
+	"github.com/botopolis/slack/internal/webapi"
+	"github.com/nlopes/slack"
+)
+
+// MessageRef identifies a message the bot sent (see Adapter.Post), so it
+// can be updated or deleted later on
+type MessageRef struct {
+	Channel   string
+	Timestamp string
+}
+
+// Update replaces the text and params of a message the bot sent. Params
+// may be nil, slack.PostMessageParameters (for its attachments) or Blocks.
+func (a *Adapter) Update(ref MessageRef, m bot.Message) error {
+	if ref.Channel == "" || ref.Timestamp == "" {
+		return errors.New("No message to update")
+	}
+	if err := parseParams(a, &m); err != nil {
+		return err
+	}
+
+	values := url.Values{
+		"channel": {ref.Channel},
+		"ts":      {ref.Timestamp},
+		"text":    {m.Text},
+		"as_user": {"true"},
+	}
+
+	switch pm := m.Params.(type) {
+	case slack.PostMessageParameters:
+		// An empty list removes attachments, where leaving it out keeps them
+		if pm.Attachments == nil {
+			pm.Attachments = []slack.Attachment{}
+		}
+		attachments, err := json.Marshal(pm.Attachments)
+		if err != nil {
+			return err
+		}
+		values.Set("attachments", string(attachments))
+	case Blocks:
+		blocks, err := json.Marshal(pm.Blocks)
+		if err != nil {
+			return err
+		}
+		values.Set("blocks", string(blocks))
+	}
+
+	return retry(a.warnf, func() error {
+		var resp slack.SlackResponse
+		return webapi.Form(a.token, "chat.update", values, &resp)
+	})
+}
+
+// Delete deletes a message the bot sent
+func (a *Adapter) Delete(ref MessageRef) error {
+	if ref.Channel == "" || ref.Timestamp == "" {
+		return errors.New("No message to delete")
+	}
+
+	values := url.Values{
+		"channel": {ref.Channel},
+		"ts":      {ref.Timestamp},
+		"as_user": {"true"},
+	}
+
+	return retry(a.warnf, func() error {
+		var resp slack.SlackResponse
+		return webapi.Form(a.token, "chat.delete", values, &resp)
+	})
+}
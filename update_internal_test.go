@@ -0,0 +1,131 @@
+package slack
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/botopolis/bot"
+	"github.com/botopolis/bot/mock"
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+// watchRTM reports the IDs of messages sent over RTM until the returned
+// func is called
+func watchRTM() (<-chan int, func()) {
+	ids := make(chan int, 1)
+	sentRTM = func(msg *slack.OutgoingMessage) { ids <- msg.ID }
+	return ids, func() { sentRTM = func(*slack.OutgoingMessage) {} }
+}
+
+func TestProxySend_ack(t *testing.T) {
+	ids, done := watchRTM()
+	defer done()
+
+	p := newProxy(newAdapter("xoxb-1"))
+	p.Robot = &bot.Robot{Logger: mock.NewLogger()}
+	in := make(chan slack.RTMEvent, 1)
+	go p.Forward(p.splitAcks(in), make(chan bot.Message))
+	defer close(in)
+
+	// Nobody takes this message, so Forward is stuck
+	in <- slack.RTMEvent{Type: "message", Data: &slack.MessageEvent{Msg: slack.Msg{Channel: "C1234"}}}
+
+	refs := make(chan MessageRef)
+	go func() {
+		ref, err := p.Send(bot.Message{Room: "C1234", Text: "Deploy running"})
+		assert.NoError(t, err)
+		refs <- ref
+	}()
+
+	in <- slack.RTMEvent{Type: "ack", Data: &slack.AckMessage{ReplyTo: <-ids, Timestamp: "1.2"}}
+	assert.Equal(t, MessageRef{Channel: "C1234", Timestamp: "1.2"}, <-refs)
+	assert.Empty(t, p.acks)
+}
+
+func TestProxySend_ackError(t *testing.T) {
+	ids, done := watchRTM()
+	defer done()
+
+	p := newProxy(newAdapter("xoxb-1"))
+	in := make(chan slack.RTMEvent)
+	go p.Forward(p.splitAcks(in), make(chan bot.Message))
+	defer close(in)
+
+	rejected := errors.New("channel_not_found")
+	cases := []struct {
+		Event slack.RTMEvent
+		Err   error
+	}{
+		{slack.RTMEvent{Type: "ack_error", Data: &slack.AckErrorEvent{ErrorObj: rejected}}, &slack.AckErrorEvent{ErrorObj: rejected}},
+		{slack.RTMEvent{Type: "ack_error", Data: &slack.RateLimitEvent{}}, &slack.RateLimitEvent{}},
+	}
+	for _, c := range cases {
+		errs := make(chan error)
+		go func() {
+			_, err := p.Send(bot.Message{Room: "C1234", Text: "Deploy running"})
+			errs <- err
+		}()
+
+		<-ids
+		in <- c.Event
+		assert.Equal(t, c.Err, <-errs)
+	}
+	assert.Empty(t, p.acks)
+}
+
+func TestProxySend_ackTimeout(t *testing.T) {
+	ackTimeout = time.Millisecond
+	defer func() { ackTimeout = 10 * time.Second }()
+
+	p := newProxy(newAdapter("xoxb-1"))
+	_, err := p.Send(bot.Message{Room: "C1234", Text: "Deploy running", Params: Thread{Timestamp: "1.1"}})
+	assert.Equal(t, errAckTimeout, err)
+	assert.Empty(t, p.acks)
+}
+
+func TestAdapterUpdate(t *testing.T) {
+	forms := make(map[string]map[string][]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		forms[r.URL.Path] = r.PostForm
+		w.Write([]byte(`{"ok":true,"channel":"C1234","ts":"1.2"}`))
+	}))
+	defer server.Close()
+
+	defer useAPI(server.URL)()
+
+	a := New("xoxb-1")
+	a.Robot = &bot.Robot{}
+	ref, err := a.Post(bot.Message{Room: "C1234", Params: testBlocks})
+	assert.NoError(t, err)
+	assert.Equal(t, MessageRef{Channel: "C1234", Timestamp: "1.2"}, ref)
+
+	assert.NoError(t, a.Update(ref, bot.Message{Text: "Deployed"}))
+	update := forms["/chat.update"]
+	assert.Equal(t, "C1234", update["channel"][0])
+	assert.Equal(t, "1.2", update["ts"][0])
+	assert.Equal(t, "Deployed", update["text"][0])
+	assert.Empty(t, update["blocks"])
+	assert.Empty(t, update["attachments"])
+
+	assert.NoError(t, a.Update(ref, bot.Message{Params: testBlocks}))
+	blocks, _ := json.Marshal(testBlocks.Blocks)
+	update = forms["/chat.update"]
+	assert.Equal(t, testBlocks.fallback(), update["text"][0])
+	assert.Equal(t, string(blocks), update["blocks"][0])
+
+	assert.NoError(t, a.Update(ref, bot.Message{Text: "Deployed", Params: slack.PostMessageParameters{}}))
+	assert.Equal(t, "[]", forms["/chat.update"]["attachments"][0])
+
+	assert.NoError(t, a.Delete(ref))
+	assert.Equal(t, "C1234", forms["/chat.delete"]["channel"][0])
+	assert.Equal(t, "1.2", forms["/chat.delete"]["ts"][0])
+
+	assert.Error(t, a.Update(MessageRef{}, bot.Message{Text: "Deployed"}))
+	assert.Error(t, a.Delete(MessageRef{Channel: "C1234"}))
+}
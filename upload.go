@@ -0,0 +1,180 @@
+package slack
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/botopolis/bot"
+	"github.com/botopolis/slack/internal/webapi"
+	"github.com/nlopes/slack"
+)
+
+// Upload describes a file to share with Adapter.Upload
+type Upload struct {
+	Filename string
+	Title    string
+	// Snippet uploads the file as a text snippet, with Filetype's syntax
+	// highlighting (such as diff or go) if given. Otherwise it's a
+	// regular file, whose type Slack works out from its content.
+	Snippet  bool
+	Filetype string
+	// Comment is posted along with the file
+	Comment string
+
+	// Rooms to share the file in, by name or ID. The file is private to
+	// the bot if there are none.
+	Rooms []string
+	// ThreadTimestamp shares the file in a thread (of a single room)
+	ThreadTimestamp string
+
+	// Size of the file, if known. Otherwise it's worked out by seeking to
+	// the end of the reader if it can, or by copying it to a temporary
+	// file first.
+	Size int64
+}
+
+// Upload shares the content of r as a file, returning its ID. The content
+// is streamed to Slack rather than held in memory.
+func (a *Adapter) Upload(r io.Reader, u Upload) (string, error) {
+	if u.Filename == "" {
+		return "", errors.New("No filename provided")
+	}
+
+	rooms := make([]string, 0, len(u.Rooms))
+	for _, room := range u.Rooms {
+		m := bot.Message{Room: room}
+		if err := parseRoom(a, &m); err != nil {
+			return "", err
+		}
+		rooms = append(rooms, m.Room)
+	}
+
+	size := u.Size
+	if size <= 0 {
+		sized, n, cleanup, err := measure(r)
+		if err != nil {
+			return "", err
+		}
+		defer cleanup()
+		r, size = sized, n
+	}
+
+	values := url.Values{
+		"filename": {u.Filename},
+		"length":   {strconv.FormatInt(size, 10)},
+	}
+	if u.Snippet {
+		values.Set("snippet_type", u.Filetype)
+		if u.Filetype == "" {
+			values.Set("snippet_type", "text")
+		}
+	}
+
+	var target struct {
+		slack.SlackResponse
+		UploadURL string `json:"upload_url"`
+		FileID    string `json:"file_id"`
+	}
+	if err := retry(a.warnf, func() error {
+		return webapi.Form(a.token, "files.getUploadURLExternal", values, &target)
+	}); err != nil {
+		return "", err
+	}
+
+	if err := sendFile(target.UploadURL, r, size); err != nil {
+		return "", err
+	}
+
+	title := u.Title
+	if title == "" {
+		title = u.Filename
+	}
+	files, err := json.Marshal([]map[string]string{{"id": target.FileID, "title": title}})
+	if err != nil {
+		return "", err
+	}
+	values = url.Values{"files": {string(files)}}
+	if len(rooms) > 0 {
+		values.Set("channels", strings.Join(rooms, ","))
+	}
+	if u.Comment != "" {
+		values.Set("initial_comment", u.Comment)
+	}
+	if u.ThreadTimestamp != "" {
+		values.Set("thread_ts", u.ThreadTimestamp)
+	}
+
+	err = retry(a.warnf, func() error {
+		var resp slack.SlackResponse
+		return webapi.Form(a.token, "files.completeUploadExternal", values, &resp)
+	})
+	return target.FileID, err
+}
+
+// measure works out how much is left to read from r, returning a reader
+// for it and a function to clean up once it's been read. Readers which
+// can't seek are copied to a temporary file.
+func measure(r io.Reader) (io.Reader, int64, func(), error) {
+	noop := func() {}
+	if s, ok := r.(io.Seeker); ok {
+		start, err := s.Seek(0, io.SeekCurrent)
+		if err == nil {
+			end, err := s.Seek(0, io.SeekEnd)
+			if err != nil {
+				return nil, 0, noop, err
+			}
+			if _, err := s.Seek(start, io.SeekStart); err != nil {
+				return nil, 0, noop, err
+			}
+			return r, end - start, noop, nil
+		}
+	}
+
+	f, err := ioutil.TempFile("", "slack-upload-")
+	if err != nil {
+		return nil, 0, noop, err
+	}
+	cleanup := func() {
+		f.Close()
+		os.Remove(f.Name())
+	}
+
+	n, err := io.Copy(f, r)
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		cleanup()
+		return nil, 0, noop, err
+	}
+	return f, n, cleanup, nil
+}
+
+// sendFile streams a file's content to the URL Slack gave us for it
+func sendFile(uploadURL string, r io.Reader, size int64) error {
+	req, err := http.NewRequest("POST", uploadURL, ioutil.NopCloser(io.LimitReader(r, size)))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("slack: File upload returned %s", resp.Status)
+	}
+	return nil
+}
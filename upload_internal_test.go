@@ -0,0 +1,96 @@
+package slack
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdapterUpload(t *testing.T) {
+	var (
+		server   *httptest.Server
+		target   map[string][]string
+		uploaded string
+		length   int64
+		complete map[string][]string
+	)
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/files.getUploadURLExternal":
+			r.ParseForm()
+			target = r.PostForm
+			w.Write([]byte(`{"ok":true,"upload_url":"` + server.URL + `/upload","file_id":"F1234"}`))
+		case "/upload":
+			b, _ := ioutil.ReadAll(r.Body)
+			uploaded, length = string(b), r.ContentLength
+		case "/files.completeUploadExternal":
+			r.ParseForm()
+			complete = r.PostForm
+			w.Write([]byte(`{"ok":true,"files":[{"id":"F1234"}]}`))
+		default:
+			t.Errorf("Unexpected call to %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	defer useAPI(server.URL)()
+
+	store := newTestStore()
+	store.Channel.ID = "C1234"
+	store.Channel.Name = "builds"
+
+	a := New("xoxb-1")
+	a.Store = store
+	log := "--- FAIL: TestEverything\n"
+
+	// Readers which can seek are measured in place
+	id, err := a.Upload(strings.NewReader(log), Upload{
+		Filename:        "build.log",
+		Title:           "Build #42",
+		Comment:         "Build failed",
+		Rooms:           []string{"builds", "C4321"},
+		ThreadTimestamp: "1.1",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "F1234", id)
+	assert.Equal(t, "build.log", target["filename"][0])
+	assert.Equal(t, "25", target["length"][0])
+	assert.Empty(t, target["snippet_type"])
+	assert.Equal(t, log, uploaded)
+	assert.Equal(t, int64(len(log)), length)
+	assert.JSONEq(t, `[{"id":"F1234","title":"Build #42"}]`, complete["files"][0])
+	assert.Equal(t, "C1234,C4321", complete["channels"][0])
+	assert.Equal(t, "Build failed", complete["initial_comment"][0])
+	assert.Equal(t, "1.1", complete["thread_ts"][0])
+
+	// Others are copied to a temporary file first
+	diff := "-old\n+new\n"
+	_, err = a.Upload(io.MultiReader(strings.NewReader(diff)), Upload{Filename: "fix.diff", Snippet: true, Filetype: "diff"})
+	assert.NoError(t, err)
+	assert.Equal(t, "diff", target["snippet_type"][0])
+	assert.Equal(t, "10", target["length"][0])
+	assert.Equal(t, diff, uploaded)
+	assert.JSONEq(t, `[{"id":"F1234","title":"fix.diff"}]`, complete["files"][0])
+	assert.Empty(t, complete["channels"])
+
+	_, err = a.Upload(strings.NewReader(log), Upload{Filename: "build.log", Rooms: []string{"random"}})
+	assert.Error(t, err)
+	_, err = a.Upload(strings.NewReader(log), Upload{})
+	assert.Error(t, err)
+}
+
+func TestMeasure(t *testing.T) {
+	r := strings.NewReader("skip:content")
+	r.Seek(5, io.SeekStart)
+	sized, n, cleanup, err := measure(r)
+	assert.NoError(t, err)
+	defer cleanup()
+	assert.Equal(t, int64(7), n)
+	b, _ := ioutil.ReadAll(sized)
+	assert.Equal(t, "content", string(b))
+}
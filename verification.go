@@ -0,0 +1,18 @@
+package slack
+
+import "time"
+
+// Verification is how webhooks check that requests came from Slack, on top
+// of their signing secret. It's shared by the Events API transport and the
+// action and command plugins.
+type Verification struct {
+	// SigningSecrets are accepted as well as the signing secret, so that
+	// it can be rotated without rejecting requests.
+	SigningSecrets []string
+	// SecretsProvider, when set, is asked for the secrets to accept on
+	// each request instead of the signing secret and SigningSecrets.
+	SecretsProvider func() ([]string, error)
+	// Tolerance is how old a request may be before it's rejected.
+	// Defaults to 5 minutes.
+	Tolerance time.Duration
+}
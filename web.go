@@ -0,0 +1,64 @@
+package slack
+
+import (
+	"github.com/botopolis/bot"
+	"github.com/nlopes/slack"
+)
+
+// webProxy is the base for transports that have no RTM socket to write
+// to. Everything outbound goes through the web API instead.
+type webProxy struct{ *proxy }
+
+func newWebProxy(a *Adapter) *webProxy {
+	return &webProxy{&proxy{
+		Adapter: a,
+		stop:    make(chan struct{}),
+		backoff: backoff{Min: reconnectMin, Max: reconnectMax},
+	}}
+}
+
+func (p *webProxy) Send(m bot.Message) (MessageRef, error) {
+	var thread Thread
+	switch params := m.Params.(type) {
+	case nil:
+	case Thread:
+		thread = params
+	default:
+		return p.proxy.Send(m)
+	}
+
+	pm := slack.NewPostMessageParameters()
+	pm.AsUser = true
+	pm.User = p.BotID
+	pm.EscapeText = false
+	pm.ThreadTimestamp = thread.Timestamp
+	pm.ReplyBroadcast = thread.Broadcast
+	m.Params = pm
+
+	return p.proxy.Send(m)
+}
+
+func (p *webProxy) React(m bot.Message) error {
+	msg := m.Envelope.(slack.Message)
+	msgRef := slack.NewRefToMessage(msg.Channel, msg.Timestamp)
+	return p.Client.AddReaction(m.Text, msgRef)
+}
+
+// connected builds the ConnectedEvent RTM would have given us, asking
+// the web API who we are.
+func (p *webProxy) connected(count int) (slack.RTMEvent, error) {
+	auth, err := p.Client.AuthTest()
+	if err != nil {
+		return slack.RTMEvent{}, err
+	}
+
+	return slack.RTMEvent{
+		Type: "connected",
+		Data: &slack.ConnectedEvent{
+			ConnectionCount: count,
+			Info: &slack.Info{
+				User: &slack.UserDetails{ID: auth.UserID, Name: auth.User},
+			},
+		},
+	}, nil
+}